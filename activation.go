@@ -0,0 +1,49 @@
+package precise
+
+import "time"
+
+// Activation describes a single detection of the wake word
+type Activation struct {
+	Time        time.Time
+	Probability float32
+	SampleRate  int
+	// Position is the stream offset, in samples, of the end of the chunk that
+	// triggered the activation
+	Position int64
+	// Audio is a copy of the pre-roll buffer at the time of activation,
+	// ending with the chunk that triggered it
+	Audio []int16
+	// Keyword is the estimated position of the keyword, if keyword location is enabled
+	Keyword Segment
+	// Broadcast is set when the activation is part of a burst across many streams, see BroadcastCoordinator
+	Broadcast bool
+}
+
+// Offset returns the time from the start of the stream to the activation
+func (a Activation) Offset() time.Duration {
+	return time.Duration(a.Position) * time.Second / time.Duration(a.SampleRate)
+}
+
+// KeywordAudio returns the part of Audio containing the keyword
+func (a Activation) KeywordAudio() []int16 {
+	offset := a.Position - int64(len(a.Audio))
+
+	start := a.Keyword.Start - offset
+	end := a.Keyword.End - offset
+
+	if start < 0 {
+		start = 0
+	}
+
+	if end > int64(len(a.Audio)) {
+		end = int64(len(a.Audio))
+	}
+
+	if start >= end {
+		return nil
+	}
+
+	return a.Audio[start:end]
+}
+
+type ActivationEventFunc func(a Activation)
@@ -0,0 +1,233 @@
+package precise
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type AGCOption func(*AGC)
+
+// WithAGCTarget sets the RMS level, from 0 to 1, the AGC aims for
+func WithAGCTarget(level float64) AGCOption {
+	return func(a *AGC) {
+		a.target = level
+	}
+}
+
+// WithAGCAttack sets how quickly the gain is reduced when the input gets louder
+func WithAGCAttack(d time.Duration) AGCOption {
+	return func(a *AGC) {
+		a.attack = d
+	}
+}
+
+// WithAGCRelease sets how quickly the gain is increased when the input gets quieter
+func WithAGCRelease(d time.Duration) AGCOption {
+	return func(a *AGC) {
+		a.release = d
+	}
+}
+
+// WithAGCMaxGain sets the largest gain applied, such as 10 for 20dB
+func WithAGCMaxGain(gain float64) AGCOption {
+	return func(a *AGC) {
+		a.maxGain = gain
+	}
+}
+
+// WithAGCNoiseFloor sets the RMS level below which the gain is held, so background noise isn't boosted
+func WithAGCNoiseFloor(level float64) AGCOption {
+	return func(a *AGC) {
+		a.noiseFloor = level
+	}
+}
+
+// NewAGC creates an automatic gain control stage for audio at sampleRate.
+// An AGC keeps state for a single stream, so each Listener needs its own.
+func NewAGC(sampleRate int, opts ...AGCOption) *AGC {
+	a := &AGC{
+		blockSize:  sampleRate / 100,
+		target:     0.1,
+		attack:     10 * time.Millisecond,
+		release:    500 * time.Millisecond,
+		maxGain:    10,
+		noiseFloor: 0.002,
+		gain:       1,
+		lock:       new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	// Blocks are 10ms, but at least a sample at very low sample rates
+	if a.blockSize < 1 {
+		a.blockSize = 1
+	}
+
+	blockT := float64(a.blockSize) / float64(sampleRate)
+
+	a.attackCoef = 1 - math.Exp(-blockT/a.attack.Seconds())
+	a.releaseCoef = 1 - math.Exp(-blockT/a.release.Seconds())
+
+	a.resetStats()
+
+	return a
+}
+
+// AGC is a Processor which adjusts the level of audio towards a target, for quiet or clipping microphones
+type AGC struct {
+	blockSize   int
+	target      float64
+	attack      time.Duration
+	release     time.Duration
+	attackCoef  float64
+	releaseCoef float64
+	maxGain     float64
+	noiseFloor  float64
+	gain        float64
+	gainSum     float64
+	stats       AGCStats
+	lock        *sync.Mutex
+}
+
+// AGCStats describes the gain applied by an AGC, to tell bad microphones apart from model misses
+type AGCStats struct {
+	// Gain is the current gain
+	Gain float64
+	// MinGain, MaxGain and MeanGain describe the gain applied to every sample
+	MinGain  float64
+	MaxGain  float64
+	MeanGain float64
+	// Level is the RMS level of the last block of input
+	Level float64
+	// InputClipped is the number of input samples at full scale, a sign of a microphone which is too loud
+	InputClipped int64
+	// Clipped is the number of samples clipped after the gain was applied
+	Clipped int64
+	Samples int64
+}
+
+// Process applies the gain to samples, returning a new slice
+func (a *AGC) Process(samples []int16) []int16 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	out := make([]int16, 0, len(samples))
+
+	for len(samples) >= a.blockSize {
+		out = a.processBlock(out, samples[:a.blockSize])
+		samples = samples[a.blockSize:]
+	}
+
+	// The remainder is too short to measure, so the current gain is applied
+	if len(samples) > 0 {
+		out = a.apply(out, samples, a.gain, a.gain)
+	}
+
+	return out
+}
+
+// processBlock updates the gain from a block of input, then applies it
+func (a *AGC) processBlock(out, block []int16) []int16 {
+	level := rms(block)
+	a.stats.Level = level
+
+	previous := a.gain
+
+	if level > a.noiseFloor {
+		desired := a.target / level
+
+		if desired > a.maxGain {
+			desired = a.maxGain
+		}
+
+		if desired < a.gain {
+			a.gain += a.attackCoef * (desired - a.gain)
+		} else {
+			a.gain += a.releaseCoef * (desired - a.gain)
+		}
+	}
+
+	return a.apply(out, block, previous, a.gain)
+}
+
+// apply ramps the gain from start to end over samples, appending the result to out
+func (a *AGC) apply(out, samples []int16, start, end float64) []int16 {
+	step := (end - start) / float64(len(samples))
+
+	for i, s := range samples {
+		gain := start + step*float64(i+1)
+
+		if s == math.MaxInt16 || s == math.MinInt16 {
+			a.stats.InputClipped++
+		}
+
+		v := float64(s) * gain
+
+		if v > math.MaxInt16 {
+			v = math.MaxInt16
+			a.stats.Clipped++
+		} else if v < math.MinInt16 {
+			v = math.MinInt16
+			a.stats.Clipped++
+		}
+
+		out = append(out, int16(v))
+
+		if gain < a.stats.MinGain {
+			a.stats.MinGain = gain
+		}
+
+		if gain > a.stats.MaxGain {
+			a.stats.MaxGain = gain
+		}
+
+		a.gainSum += gain
+	}
+
+	a.stats.Samples += int64(len(samples))
+
+	return out
+}
+
+// Reset returns the gain to 1, keeping the stats
+func (a *AGC) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.gain = 1
+}
+
+// Stats returns the gain applied so far
+func (a *AGC) Stats() AGCStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	stats := a.stats
+	stats.Gain = a.gain
+
+	if stats.Samples > 0 {
+		stats.MeanGain = a.gainSum / float64(stats.Samples)
+	} else {
+		stats.MinGain = 0
+	}
+
+	return stats
+}
+
+// ResetStats clears the stats, such as after reporting them
+func (a *AGC) ResetStats() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.resetStats()
+}
+
+func (a *AGC) resetStats() {
+	a.stats = AGCStats{
+		MinGain: math.Inf(1),
+	}
+	a.gainSum = 0
+}
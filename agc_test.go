@@ -0,0 +1,161 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"math"
+	"testing"
+	"time"
+)
+
+// sine creates n samples of a 440Hz tone with the given amplitude, from 0 to 1
+func sine(amplitude float64, n int) []int16 {
+	samples := make([]int16, n)
+
+	for i := range samples {
+		samples[i] = int16(amplitude * 32767 * math.Sin(2*math.Pi*440*float64(i)/16000))
+	}
+
+	return samples
+}
+
+func TestAGC(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		amplitude float64
+		gain      float64
+	}{
+		// An RMS of 0.0141 needs a gain of ~7 to reach the target
+		{"quiet", 0.02, 7.07},
+		// Quieter than the max gain can correct
+		{"very quiet", 0.005, 10},
+		// An RMS of 0.636 needs a gain of ~0.157
+		{"loud", 0.9, 0.157},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			agc := NewAGC(16000)
+
+			var out []int16
+
+			// Two seconds in runner sized chunks
+			input := sine(c.amplitude, 32000)
+
+			for i := 0; i < len(input); i += 1024 {
+				end := i + 1024
+
+				if end > len(input) {
+					end = len(input)
+				}
+
+				out = append(out, agc.Process(input[i:end])...)
+			}
+
+			if len(out) != len(input) {
+				t.Fatal("expected", len(input), "samples, got", len(out))
+			}
+
+			stats := agc.Stats()
+
+			if math.Abs(stats.Gain-c.gain)/c.gain > 0.05 {
+				t.Fatalf("expected gain %.3f, got %.3f", c.gain, stats.Gain)
+			}
+
+			expected := math.Min(0.1, c.amplitude/math.Sqrt2*10)
+
+			if level := rms(out[24000:]); math.Abs(level-expected)/expected > 0.05 {
+				t.Fatalf("expected level %.3f, got %.3f", expected, level)
+			}
+
+			if stats.Samples != 32000 || stats.MaxGain > 10 || stats.MinGain > stats.MaxGain {
+				t.Fatalf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestAGCLowSampleRate(t *testing.T) {
+	done := make(chan []int16)
+
+	// Sample rates below 100Hz would have empty blocks
+	go func() {
+		done <- NewAGC(50).Process(sine(0.5, 100))
+	}()
+
+	select {
+	case out := <-done:
+		if len(out) != 100 {
+			t.Fatal("expected 100 samples, got", len(out))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("processing did not finish")
+	}
+}
+
+func TestAGCNoiseFloor(t *testing.T) {
+	agc := NewAGC(16000, WithAGCRelease(100*time.Millisecond))
+
+	agc.Process(sine(0.9, 16000))
+	gain := agc.Stats().Gain
+
+	// Silence holds the gain, rather than boosting noise
+	agc.Process(make([]int16, 16000))
+
+	if agc.Stats().Gain != gain {
+		t.Fatal("expected gain to be held during silence")
+	}
+}
+
+// countingProcessor counts the samples it processes, and inverts them
+type countingProcessor struct {
+	samples int
+	resets  int
+}
+
+func (p *countingProcessor) Process(samples []int16) []int16 {
+	p.samples += len(samples)
+
+	out := make([]int16, len(samples))
+
+	for i, s := range samples {
+		out[i] = -s
+	}
+
+	return out
+}
+
+func (p *countingProcessor) Reset() {
+	p.resets++
+}
+
+func TestListenerProcessor(t *testing.T) {
+	processor := &countingProcessor{}
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		return 0, nil
+	}), NewParams(), WithProcessor(processor))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := sequence(1, 2000)
+
+	if _, err := l.Update(input); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Skip(input)
+
+	if processor.samples != 4000 {
+		t.Fatal("expected processor to see 4000 samples, got", processor.samples)
+	}
+
+	if input[0] != 1 {
+		t.Fatal("processor modified the input")
+	}
+
+	l.Reset()
+
+	if processor.resets != 1 {
+		t.Fatal("expected listener reset to reset processors")
+	}
+}
@@ -0,0 +1,223 @@
+package precise
+
+import (
+	"sync"
+	"time"
+)
+
+// Arbitration is the result of arbitrating between activations in a zone
+type Arbitration struct {
+	Zone string
+	// Stream is the winning stream, and Activation its activation
+	Stream     string
+	Activation Activation
+	// Energy is the RMS level of the winning activation's audio, from 0 to 1
+	Energy float64
+	// Losers are the other streams which activated, and have been told to ignore it
+	Losers []string
+}
+
+// ArbitrationFunc is called with the winning activation in a zone
+type ArbitrationFunc func(a Arbitration)
+
+type ArbiterOption func(*Arbiter)
+
+// WithArbitrationWindow sets how long the arbiter waits for other streams in a zone after the first activation
+func WithArbitrationWindow(d time.Duration) ArbiterOption {
+	return func(a *Arbiter) {
+		a.window = d
+	}
+}
+
+// WithArbitrationMargin sets how close, in probability, activations must be to the best
+// for the one with the most energy to win
+func WithArbitrationMargin(margin float32) ArbiterOption {
+	return func(a *Arbiter) {
+		a.margin = margin
+	}
+}
+
+// WithArbitrationFunc sets the func called with the winning activation in each zone
+func WithArbitrationFunc(f ArbitrationFunc) ArbiterOption {
+	return func(a *Arbiter) {
+		a.OnArbitration = f
+	}
+}
+
+// NewArbiter creates an arbiter, which runners join with WithArbiter
+func NewArbiter(opts ...ArbiterOption) *Arbiter {
+	a := &Arbiter{
+		window:  500 * time.Millisecond,
+		margin:  0.05,
+		zones:   make(map[string]string),
+		runners: make(map[string]*Runner),
+		pending: make(map[string][]arbitrationCandidate),
+		lock:    new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Arbiter picks a single winner when one wake word is heard by several streams in the same zone,
+// such as devices in one room or a user connected on several clients, so the app responds only once.
+// Runners hold their activation handlers until the arbiter decides, which delays them by the window.
+type Arbiter struct {
+	window  time.Duration
+	margin  float32
+	zones   map[string]string
+	runners map[string]*Runner
+	pending map[string][]arbitrationCandidate
+	lock    *sync.Mutex
+
+	OnArbitration ArbitrationFunc
+}
+
+// arbitrationCandidate is an activation waiting for arbitration
+type arbitrationCandidate struct {
+	stream     string
+	activation Activation
+	energy     float64
+}
+
+// Zone adds streams to a zone. Streams which aren't in a zone are arbitrated on their own.
+func (a *Arbiter) Zone(zone string, streams ...string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, stream := range streams {
+		a.zones[stream] = zone
+	}
+}
+
+// zone returns the zone a stream belongs to
+func (a *Arbiter) zone(stream string) string {
+	if zone, ok := a.zones[stream]; ok {
+		return zone
+	}
+
+	return stream
+}
+
+// register sets the runner told to ignore activations the stream loses
+func (a *Arbiter) register(stream string, r *Runner) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.runners[stream] = r
+}
+
+// unregister removes the runner for a stream, if it hasn't been replaced
+func (a *Arbiter) unregister(stream string, r *Runner) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.runners[stream] == r {
+		delete(a.runners, stream)
+	}
+}
+
+// report adds an activation to its zone, starting the window if it's the first
+func (a *Arbiter) report(stream string, activation Activation, energy float64) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	zone := a.zone(stream)
+
+	candidates, ok := a.pending[zone]
+
+	if !ok {
+		time.AfterFunc(a.window, func() {
+			a.decide(zone)
+		})
+	}
+
+	a.pending[zone] = append(candidates, arbitrationCandidate{
+		stream:     stream,
+		activation: activation,
+		energy:     energy,
+	})
+}
+
+// decide picks the winner in a zone once the window has passed
+func (a *Arbiter) decide(zone string) {
+	a.lock.Lock()
+
+	candidates := a.pending[zone]
+	delete(a.pending, zone)
+
+	// The most energetic of the activations within the margin of the best probability wins,
+	// as the closest microphone hears the loudest and usually clearest audio
+	var best float32
+
+	for _, c := range candidates {
+		if c.activation.Probability > best {
+			best = c.activation.Probability
+		}
+	}
+
+	winner := -1
+
+	for i, c := range candidates {
+		if c.activation.Probability < best-a.margin {
+			continue
+		}
+
+		if winner < 0 || c.energy > candidates[winner].energy {
+			winner = i
+		}
+	}
+
+	res := Arbitration{
+		Zone:       zone,
+		Stream:     candidates[winner].stream,
+		Activation: candidates[winner].activation,
+		Energy:     candidates[winner].energy,
+	}
+
+	won := a.runners[res.Stream]
+
+	var losers []*Runner
+
+	for _, c := range candidates {
+		// A stream may activate more than once in the window
+		if c.stream == res.Stream || containsString(res.Losers, c.stream) {
+			continue
+		}
+
+		res.Losers = append(res.Losers, c.stream)
+
+		if r, ok := a.runners[c.stream]; ok {
+			losers = append(losers, r)
+		}
+	}
+
+	onArbitration := a.OnArbitration
+
+	a.lock.Unlock()
+
+	for _, r := range losers {
+		r.ignore.Store(true)
+	}
+
+	if won != nil {
+		won.won(res.Activation)
+	}
+
+	if onArbitration != nil {
+		onArbitration(res)
+	}
+}
+
+func containsString(s []string, v string) bool {
+	for _, item := range s {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,169 @@
+package precise
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArbiter(t *testing.T) {
+	results := make(chan Arbitration, 2)
+
+	a := NewArbiter(WithArbitrationWindow(200*time.Millisecond), WithArbitrationFunc(func(res Arbitration) {
+		results <- res
+	}))
+
+	a.Zone("living room", "speaker", "tv", "phone")
+
+	utterances := make(chan string, 4)
+
+	runners := make(map[string]*Runner)
+	counts := make(map[string]func() int)
+
+	// The speaker is closest, so hears the wake word loudest
+	levels := map[string]int16{"speaker": 3, "tv": 2, "phone": 1, "office": 1}
+
+	for stream := range levels {
+		stream := stream
+
+		r, count := newTriggeringRunner(t, WithArbiter(a, stream), WithCapture(UtteranceHandlerFunc(func(u Utterance) {
+			utterances <- stream
+		})))
+
+		runners[stream] = r
+		counts[stream] = count
+	}
+
+	// Every stream hears the wake word at once
+	audio := modulatedTone(1, 48000)
+
+	for i := 0; i < len(audio); i += 320 {
+		for stream, r := range runners {
+			chunk := make([]int16, 320)
+
+			for j := range chunk {
+				chunk[j] = audio[i+j] / 3 * levels[stream]
+			}
+
+			r.Queue(chunk)
+		}
+	}
+
+	arbitrations := make(map[string]Arbitration)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case res := <-results:
+			arbitrations[res.Zone] = res
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for arbitration")
+		}
+	}
+
+	room := arbitrations["living room"]
+
+	if room.Stream != "speaker" || len(room.Losers) != 2 {
+		t.Fatalf("expected the speaker to win over two losers, got %s over %v", room.Stream, room.Losers)
+	}
+
+	// Streams outside a zone are arbitrated on their own
+	if office := arbitrations["office"]; office.Stream != "office" || len(office.Losers) != 0 {
+		t.Fatalf("expected the office to win on its own, got %s over %v", office.Stream, office.Losers)
+	}
+
+	// Winners are passed their activation before the arbitration func is called, so once each
+	// runner has handled its queue only the winners' handlers have been called
+	for stream, count := range counts {
+		activations := count()
+
+		expected := 0
+
+		if stream == "speaker" || stream == "office" {
+			expected = 1
+		}
+
+		if activations != expected {
+			t.Fatalf("expected %d activations for %s, got %d", expected, stream, activations)
+		}
+	}
+
+	// Only the winners capture the command, with losers ignoring the activation from their next chunk
+	silence := make([]int16, 320)
+
+	for stream, r := range runners {
+		r.Queue(silence)
+		r.Queue(nil)
+
+		if winner := stream == "speaker" || stream == "office"; r.capture.active != winner {
+			t.Fatalf("expected capturing to be %v for %s", winner, stream)
+		}
+	}
+
+	for _, r := range runners {
+		for i := 0; i < 100; i++ {
+			r.Queue(silence)
+		}
+	}
+
+	captured := make(map[string]int)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case stream := <-utterances:
+			captured[stream]++
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for utterances")
+		}
+	}
+
+	if captured["speaker"] != 1 || captured["office"] != 1 {
+		t.Fatalf("expected utterances from the speaker and office, got %v", captured)
+	}
+}
+
+func TestArbiterStoppedWinner(t *testing.T) {
+	results := make(chan Arbitration, 1)
+
+	a := NewArbiter(WithArbitrationWindow(300*time.Millisecond), WithArbitrationFunc(func(res Arbitration) {
+		results <- res
+	}))
+
+	exited := make(chan struct{})
+
+	speaker, _ := newTriggeringRunner(t, WithArbiter(a, "speaker"), WithExitFunc(func(err error) {
+		close(exited)
+	}))
+
+	audio := modulatedTone(1, 48000)
+
+	// The winner stops as soon as its activation is waiting for the arbiter,
+	// and its goroutine exits once it has finished its chunk
+	for i := 0; i < len(audio); i += 320 {
+		speaker.Queue(audio[i : i+320])
+
+		a.lock.Lock()
+		pending := len(a.pending)
+		a.lock.Unlock()
+
+		if pending > 0 {
+			break
+		}
+	}
+
+	speaker.Stop()
+
+	select {
+	case speaker.sampleCh <- queuedChunk{}:
+	case <-exited:
+	}
+
+	<-exited
+
+	select {
+	case res := <-results:
+		if res.Stream != "speaker" {
+			t.Fatal("expected the speaker to win, got", res.Stream)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("arbiter blocked on a stopped winner")
+	}
+}
@@ -0,0 +1,87 @@
+package precise
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported audio encoding")
+)
+
+// Audio is decoded audio, with interleaved samples from -1 to 1
+type Audio struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Duration returns the number of seconds of audio
+func (a *Audio) Duration() float64 {
+	return float64(len(a.Samples)) / float64(a.Channels*a.SampleRate)
+}
+
+// ConvertAudio downmixes and resamples audio to the format described by p
+func ConvertAudio(a *Audio, p Params) ([]int16, error) {
+	if p.SampleDepth != 2 {
+		return nil, fmt.Errorf("%w: %d byte samples, only 16-bit models are supported", ErrUnsupportedEncoding, p.SampleDepth)
+	}
+
+	if a.Channels < 1 || a.SampleRate < 1 {
+		return nil, fmt.Errorf("%w: %d channels at %dHz", ErrUnsupportedEncoding, a.Channels, a.SampleRate)
+	}
+
+	samples := downmix(a.Samples, a.Channels)
+
+	if a.SampleRate != p.SampleRate {
+		r := NewResampler(a.SampleRate, p.SampleRate)
+
+		samples = append(r.Process(samples), r.Flush()...)
+	}
+
+	return floatToInt16Slice(samples), nil
+}
+
+// downmix averages interleaved channels to mono
+func downmix(samples []float32, channels int) []float32 {
+	if channels == 1 {
+		return samples
+	}
+
+	out := make([]float32, len(samples)/channels)
+
+	for i := range out {
+		var sum float32
+
+		for c := 0; c < channels; c++ {
+			sum += samples[i*channels+c]
+		}
+
+		out[i] = sum / float32(channels)
+	}
+
+	return out
+}
+
+// int16ToFloat32Slice converts 16-bit samples to float32 audio
+func int16ToFloat32Slice(input []int16) []float32 {
+	output := make([]float32, len(input))
+
+	for i, s := range input {
+		output[i] = float32(float64(s) * int16Divider)
+	}
+
+	return output
+}
+
+// floatToInt16Slice converts float32 audio to 16-bit samples, clipping out of range values
+func floatToInt16Slice(input []float32) []int16 {
+	output := make([]int16, len(input))
+
+	for i, s := range input {
+		output[i] = int16(math.Max(math.Min(float64(s)*32768, math.MaxInt16), math.MinInt16))
+	}
+
+	return output
+}
@@ -0,0 +1,186 @@
+package precise
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownModelFormat = errors.New("unknown model format")
+	ErrBackendUnavailable = errors.New("model backend isn't in this build")
+)
+
+const (
+	// modelHeaderSize is the number of bytes needed to detect a model's format
+	modelHeaderSize = 8
+)
+
+// Backend loads models in a single format, such as tflite or onnx.
+// Backends register themselves when they're included in a build, see RegisterBackend.
+type Backend interface {
+	// Name returns the model format, as returned by DetectModelFormat and used in bundle manifests
+	Name() string
+	// Load loads a model from its data
+	Load(data []byte) (Model, error)
+	// LoadFile loads a model from a file
+	LoadFile(path string) (Model, error)
+}
+
+var (
+	backends    []Backend
+	backendLock sync.RWMutex
+)
+
+// RegisterBackend adds a backend. Backends registered later take priority,
+// so a backend can be replaced by registering another with the same name.
+func RegisterBackend(b Backend) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+
+	backends = append([]Backend{b}, backends...)
+}
+
+// Backends returns the names of the backends in this build, in order of priority
+func Backends() []string {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+
+	names := make([]string, 0, len(backends))
+
+	for _, b := range backends {
+		names = append(names, b.Name())
+	}
+
+	return names
+}
+
+// LookupBackend returns the backend for a model format, if it's in this build
+func LookupBackend(name string) (Backend, error) {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+
+	for _, b := range backends {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrBackendUnavailable, name)
+}
+
+// DetectModelFormat returns the format of a model from the start of its data, or its file extension,
+// whether or not its backend is in this build. It returns an empty string for unknown formats.
+func DetectModelFormat(path string, header []byte) string {
+	// tflite models are flatbuffers with the identifier after the root table offset
+	if len(header) >= 8 && string(header[4:8]) == "TFL3" {
+		return "tflite"
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tflite":
+		return "tflite"
+	case ".onnx":
+		return "onnx"
+	}
+
+	// onnx models are protobufs, which start with the IR version as field 1, a small varint
+	if len(header) >= 2 && header[0] == 0x08 && header[1] < 0x80 {
+		return "onnx"
+	}
+
+	return ""
+}
+
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	format string
+	keys   TrustedKeys
+	verify bool
+}
+
+// WithModelFormat sets the model's format, rather than detecting it
+func WithModelFormat(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.format = name
+	}
+}
+
+// WithModelKeys only loads models signed by one of the keys, see TrustedKeys.ReadFile
+func WithModelKeys(keys TrustedKeys) LoadOption {
+	return func(o *loadOptions) {
+		o.keys = keys
+		o.verify = true
+	}
+}
+
+// LoadModel loads a model with the backend for its format, detected from its data or extension
+func LoadModel(path string, opts ...LoadOption) (Model, error) {
+	o := &loadOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var data []byte
+	var err error
+
+	// Signed models are loaded from the verified data, so the file can't change in between
+	if o.verify {
+		data, err = o.keys.ReadFile(path)
+	} else {
+		data, err = readHeader(path, modelHeaderSize)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	format := o.format
+
+	if format == "" {
+		format = DetectModelFormat(path, data)
+	}
+
+	if format == "" {
+		return nil, fmt.Errorf("%s: %w", path, ErrUnknownModelFormat)
+	}
+
+	backend, err := LookupBackend(format)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if o.verify {
+		return backend.Load(data)
+	}
+
+	return backend.LoadFile(path)
+}
+
+// readHeader reads up to n bytes from the start of a file
+func readHeader(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	header := make([]byte, n)
+
+	read, err := io.ReadFull(f, header)
+
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+
+	return header[:read], nil
+}
@@ -0,0 +1,95 @@
+package precise
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectModelFormat(t *testing.T) {
+	tests := []struct {
+		path     string
+		header   []byte
+		expected string
+	}{
+		{"model.bin", []byte("\x1c\x00\x00\x00TFL3"), "tflite"},
+		{"model.onnx", []byte("\x1c\x00\x00\x00TFL3"), "tflite"},
+		{"model.tflite", nil, "tflite"},
+		{"MODEL.ONNX", nil, "onnx"},
+		{"model.bin", []byte{0x08, 0x07, 0x12, 0x07}, "onnx"},
+		{"model.bin", []byte("PK\x03\x04"), ""},
+	}
+
+	for _, test := range tests {
+		if format := DetectModelFormat(test.path, test.header); format != test.expected {
+			t.Errorf("%s %q: expected %q, got %q", test.path, test.header, test.expected, format)
+		}
+	}
+}
+
+func TestLoadModel(t *testing.T) {
+	withFakeBackend(t)
+
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	if _, err := LoadModel(write("astra.tflite", []byte{255})); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("expected ErrBackendUnavailable without a tflite backend, got %v", err)
+	}
+
+	if _, err := LoadModel(write("astra.bin", []byte{255})); !errors.Is(err, ErrUnknownModelFormat) {
+		t.Errorf("expected ErrUnknownModelFormat, got %v", err)
+	}
+
+	model, err := LoadModel(filepath.Join(dir, "astra.bin"), WithModelFormat("fake"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score, _ := model.Predict(nil); score != 1 {
+		t.Errorf("expected the fake backend to load the model, got a score of %.2f", score)
+	}
+
+	if _, err := LoadModel(filepath.Join(dir, "astra.bin"), WithModelFormat("fake"), WithModelKeys(nil)); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected an unsigned model with no trusted keys to fail with ErrSignature, got %v", err)
+	}
+
+	trusted, key := newSigningKey(t)
+
+	if _, err := LoadModel(filepath.Join(dir, "astra.bin"), WithModelFormat("fake"), WithModelKeys(TrustedKeys{trusted})); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected an unsigned model to fail with ErrSignature, got %v", err)
+	}
+
+	if err := SignModelFile(filepath.Join(dir, "astra.bin"), key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadModel(filepath.Join(dir, "astra.bin"), WithModelFormat("fake"), WithModelKeys(TrustedKeys{trusted})); err != nil {
+		t.Errorf("expected a signed model to load, got %v", err)
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	withFakeBackend(t)
+
+	RegisterBackend(fakeBackend{})
+
+	if names := Backends(); len(names) != 2 || names[0] != "fake" {
+		t.Errorf("expected both fake backends, got %v", names)
+	}
+
+	if _, err := LookupBackend("onnx"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("expected ErrBackendUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package precise
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEntryNotFound = errors.New("blacklist entry not found")
+)
+
+// BlacklistEntry is a fingerprint of audio known to cause false activations
+type BlacklistEntry struct {
+	ID          string      `json:"id"`
+	Fingerprint Fingerprint `json:"fingerprint"`
+	Note        string      `json:"note,omitempty"`
+	Added       time.Time   `json:"added"`
+}
+
+// BlacklistedFunc is called when an activation is suppressed by the blacklist
+type BlacklistedFunc func(a Activation, entry BlacklistEntry, similarity float64)
+
+type BlacklistOption func(*Blacklist)
+
+// WithBlacklistThreshold sets the similarity, from 0 to 1, at which audio matches an entry.
+// Unrelated audio has a similarity of around 0.5.
+func WithBlacklistThreshold(threshold float64) BlacklistOption {
+	return func(b *Blacklist) {
+		b.threshold = threshold
+	}
+}
+
+// NewBlacklist creates an in-memory blacklist
+func NewBlacklist(opts ...BlacklistOption) *Blacklist {
+	b := &Blacklist{
+		threshold: 0.7,
+		lock:      new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// OpenBlacklist opens a blacklist stored at path, creating it on the first Add if it doesn't exist.
+// Changes are saved to the file as they are made.
+func OpenBlacklist(path string, opts ...BlacklistOption) (*Blacklist, error) {
+	b := NewBlacklist(opts...)
+	b.path = path
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &b.entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return b, nil
+}
+
+// Blacklist holds fingerprints of audio known to cause false activations, such as adverts
+type Blacklist struct {
+	path      string
+	threshold float64
+	entries   []BlacklistEntry
+	lock      *sync.RWMutex
+}
+
+// Add adds a fingerprint to the blacklist
+func (b *Blacklist) Add(fp Fingerprint, note string) (BlacklistEntry, error) {
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return BlacklistEntry{}, err
+	}
+
+	entry := BlacklistEntry{
+		ID:          hex.EncodeToString(id),
+		Fingerprint: fp,
+		Note:        note,
+		Added:       time.Now(),
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.entries = append(b.entries, entry)
+
+	if err := b.save(); err != nil {
+		b.entries = b.entries[:len(b.entries)-1]
+		return BlacklistEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// AddActivation adds the audio of a reported false activation to the blacklist.
+// The activation must include audio, from WithPreRoll or a runner with a blacklist.
+func (b *Blacklist) AddActivation(a Activation, note string) (BlacklistEntry, error) {
+	if len(a.Audio) == 0 {
+		return BlacklistEntry{}, errors.New("activation has no audio to fingerprint")
+	}
+
+	return b.Add(NewFingerprint(a.Audio, a.SampleRate), note)
+}
+
+// Remove removes an entry from the blacklist
+func (b *Blacklist) Remove(id string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i, entry := range b.entries {
+		if entry.ID != id {
+			continue
+		}
+
+		entries := append(append([]BlacklistEntry{}, b.entries[:i]...), b.entries[i+1:]...)
+		previous := b.entries
+		b.entries = entries
+
+		if err := b.save(); err != nil {
+			b.entries = previous
+			return err
+		}
+
+		return nil
+	}
+
+	return ErrEntryNotFound
+}
+
+// Entries returns a copy of the entries in the blacklist
+func (b *Blacklist) Entries() []BlacklistEntry {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return append([]BlacklistEntry{}, b.entries...)
+}
+
+// Match returns the entry most similar to fp, if any is above the threshold
+func (b *Blacklist) Match(fp Fingerprint) (BlacklistEntry, float64, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	var best BlacklistEntry
+	var bestSimilarity float64
+
+	for _, entry := range b.entries {
+		if s := entry.Fingerprint.Similarity(fp); s > bestSimilarity {
+			best = entry
+			bestSimilarity = s
+		}
+	}
+
+	return best, bestSimilarity, bestSimilarity >= b.threshold
+}
+
+// save writes the entries to the file, if any, replacing it atomically
+func (b *Blacklist) save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(b.entries, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), b.path)
+}
@@ -0,0 +1,235 @@
+package precise
+
+import (
+	"encoding/json"
+	"gorgonia.org/tensor"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// chords creates audio with three random tones every 50ms, with distinct spectra like speech or music
+func chords(seed int64, n int) []int16 {
+	r := rand.New(rand.NewSource(seed))
+
+	samples := make([]int16, n)
+
+	var freqs [3]float64
+
+	for i := range samples {
+		if i%800 == 0 {
+			for j := range freqs {
+				freqs[j] = 300 + r.Float64()*2700
+			}
+		}
+
+		var v float64
+
+		for _, f := range freqs {
+			v += math.Sin(2 * math.Pi * f * float64(i) / 16000)
+		}
+
+		samples[i] = int16(v * 3000)
+	}
+
+	return samples
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	audio := chords(1, 32000)
+	fp := NewFingerprint(audio, 16000)
+
+	// A quieter, noisy copy, starting 100ms later
+	shifted := addNoise(audio[1600:], 0.01)
+
+	for i := range shifted {
+		shifted[i] /= 2
+	}
+
+	if s := fp.Similarity(NewFingerprint(shifted, 16000)); s < 0.75 {
+		t.Errorf("Expected shifted copy to match, got similarity %.2f", s)
+	}
+
+	if s := fp.Similarity(NewFingerprint(chords(2, 32000), 16000)); s > 0.65 {
+		t.Errorf("Expected different audio not to match, got similarity %.2f", s)
+	}
+}
+
+func TestFingerprintSilence(t *testing.T) {
+	// Zero filled gaps, such as from GapFill, are identical in unrelated clips
+	entry := append(make([]int16, 32000), chords(3, 16000)...)
+	probe := append(make([]int16, 12000), chords(4, 20000)...)
+
+	if s := NewFingerprint(entry, 16000).Similarity(NewFingerprint(probe, 16000)); s > 0.65 {
+		t.Errorf("Expected clips sharing only silence not to match, got similarity %.2f", s)
+	}
+
+	b := NewBlacklist()
+
+	if _, err := b.Add(NewFingerprint(entry, 16000), "silence then noise"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, s, ok := b.Match(NewFingerprint(probe, 16000)); ok {
+		t.Errorf("Expected no blacklist match, got similarity %.2f", s)
+	}
+
+	if s := NewFingerprint(make([]int16, 16000), 16000).Similarity(NewFingerprint(make([]int16, 16000), 16000)); s != 0 {
+		t.Errorf("Expected silence not to match silence, got similarity %.2f", s)
+	}
+}
+
+func TestFingerprintJSON(t *testing.T) {
+	fp := NewFingerprint(chords(1, 16000), 16000)
+
+	data, err := json.Marshal(fp)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Fingerprint
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) != len(fp) || decoded.Similarity(fp) != 1 {
+		t.Error("Expected fingerprint to survive a JSON round trip")
+	}
+}
+
+func TestBlacklistStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.json")
+
+	b, err := OpenBlacklist(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := b.AddActivation(Activation{Audio: chords(1, 24000), SampleRate: 16000}, "radio advert")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Add(NewFingerprint(chords(2, 24000), 16000), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenBlacklist(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reopened.Entries()) != 2 {
+		t.Fatalf("Expected 2 stored entries, got %d", len(reopened.Entries()))
+	}
+
+	match, _, ok := reopened.Match(NewFingerprint(chords(1, 24000)[800:], 16000))
+
+	if !ok || match.ID != entry.ID || match.Note != "radio advert" {
+		t.Errorf("Expected stored entry %s to match, got %+v", entry.ID, match)
+	}
+
+	if err := reopened.Remove(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reopened.Remove(entry.ID); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+
+	reopened, err = OpenBlacklist(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := reopened.Match(NewFingerprint(chords(1, 24000), 16000)); ok {
+		t.Error("Expected removed entry not to match")
+	}
+}
+
+// runBlacklisted runs audio through a runner which activates after two seconds, returning the
+// reported and blacklisted activations
+func runBlacklisted(t *testing.T, b *Blacklist, audio []int16) ([]Activation, []BlacklistEntry) {
+	calls := 0
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls > 100 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := new(sync.Mutex)
+
+	var activations []Activation
+	var blacklisted []BlacklistEntry
+
+	r := NewRunner(l, 320, WithBlacklist(b), WithActivationEventFunc(func(a Activation) {
+		lock.Lock()
+		activations = append(activations, a)
+		lock.Unlock()
+	}), WithBlacklistedFunc(func(a Activation, entry BlacklistEntry, similarity float64) {
+		lock.Lock()
+		blacklisted = append(blacklisted, entry)
+		lock.Unlock()
+	}))
+
+	defer r.Close()
+
+	for i := 0; i < len(audio); i += 320 {
+		r.Queue(audio[i : i+320])
+	}
+
+	// Wait for the last chunk to be processed
+	r.Queue(nil)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return activations, blacklisted
+}
+
+func TestRunnerBlacklist(t *testing.T) {
+	b := NewBlacklist()
+
+	activations, _ := runBlacklisted(t, b, chords(1, 48000))
+
+	if len(activations) != 1 {
+		t.Fatal("expected an activation before blacklisting, got", len(activations))
+	}
+
+	entry, err := b.AddActivation(activations[0], "false trigger")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same audio, with noise, is suppressed
+	activations, blacklisted := runBlacklisted(t, b, addNoise(chords(1, 48000), 0.01))
+
+	if len(activations) != 0 || len(blacklisted) != 1 || blacklisted[0].ID != entry.ID {
+		t.Fatalf("expected the activation to be blacklisted, got %d activations and %d blacklisted", len(activations), len(blacklisted))
+	}
+
+	// Other audio still activates
+	activations, blacklisted = runBlacklisted(t, b, chords(2, 48000))
+
+	if len(activations) != 1 || len(blacklisted) != 0 {
+		t.Fatalf("expected other audio to activate, got %d activations and %d blacklisted", len(activations), len(blacklisted))
+	}
+}
@@ -0,0 +1,182 @@
+package precise
+
+import (
+	"sync"
+	"time"
+)
+
+// BroadcastAction is what a BroadcastCoordinator does with activations in a burst
+type BroadcastAction int
+
+const (
+	// BroadcastFlag emits activations in a burst with Broadcast set
+	BroadcastFlag BroadcastAction = iota
+	// BroadcastSuppress drops activations in a burst
+	BroadcastSuppress
+)
+
+// BroadcastFunc is called for each activation found to be part of a burst.
+// Activations before the burst was detected have already been emitted, so this is
+// the only way to learn they were part of it.
+type BroadcastFunc func(stream string, a Activation)
+
+type BroadcastOption func(*BroadcastCoordinator)
+
+// WithBroadcastWindow sets how close together activations must be to count as a burst
+func WithBroadcastWindow(d time.Duration) BroadcastOption {
+	return func(c *BroadcastCoordinator) {
+		c.window = d
+	}
+}
+
+// WithBroadcastLimit sets the number of independent streams which may activate within
+// the window before further activations are treated as a broadcast
+func WithBroadcastLimit(n int) BroadcastOption {
+	return func(c *BroadcastCoordinator) {
+		c.limit = n
+	}
+}
+
+// WithBroadcastAction sets whether activations in a burst are flagged or suppressed
+func WithBroadcastAction(action BroadcastAction) BroadcastOption {
+	return func(c *BroadcastCoordinator) {
+		c.action = action
+	}
+}
+
+// WithBroadcastFunc sets the func called for each activation in a burst
+func WithBroadcastFunc(f BroadcastFunc) BroadcastOption {
+	return func(c *BroadcastCoordinator) {
+		c.OnBroadcast = f
+	}
+}
+
+// NewBroadcastCoordinator creates a coordinator, which runners join with WithBroadcastCoordinator
+func NewBroadcastCoordinator(opts ...BroadcastOption) *BroadcastCoordinator {
+	c := &BroadcastCoordinator{
+		window: time.Second,
+		limit:  3,
+		groups: make(map[string]string),
+		now:    time.Now,
+		lock:   new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// BroadcastCoordinator watches activations across many runners, catching the bursts caused
+// by the wake word being played on a popular stream or broadcast, which every listener hears at once.
+type BroadcastCoordinator struct {
+	window time.Duration
+	limit  int
+	action BroadcastAction
+	// groups maps co-located streams to the first stream in their group
+	groups     map[string]string
+	recent     []streamActivation
+	burstUntil time.Time
+	now        func() time.Time
+	lock       *sync.Mutex
+
+	OnBroadcast BroadcastFunc
+}
+
+// streamActivation is an activation reported by a stream
+type streamActivation struct {
+	stream     string
+	at         time.Time
+	activation Activation
+	flagged    bool
+}
+
+// CoLocate marks streams as co-located, such as devices in the same room, so they
+// count as a single independent stream when they activate together
+func (c *BroadcastCoordinator) CoLocate(streams ...string) {
+	if len(streams) == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	group := c.group(streams[0])
+
+	for _, stream := range streams {
+		previous := c.group(stream)
+
+		// Merge any existing group into this one
+		for s, g := range c.groups {
+			if g == previous {
+				c.groups[s] = group
+			}
+		}
+
+		c.groups[stream] = group
+	}
+}
+
+// group returns the group a stream belongs to
+func (c *BroadcastCoordinator) group(stream string) string {
+	if group, ok := c.groups[stream]; ok {
+		return group
+	}
+
+	return stream
+}
+
+// report records an activation from a stream, returning true if it is part of a burst
+func (c *BroadcastCoordinator) report(stream string, a Activation) bool {
+	c.lock.Lock()
+
+	now := c.now()
+
+	// Forget activations which are too old to be part of a burst
+	i := 0
+
+	for i < len(c.recent) && now.Sub(c.recent[i].at) > c.window {
+		i++
+	}
+
+	c.recent = append(c.recent[i:], streamActivation{stream: stream, at: now, activation: a})
+
+	groups := make(map[string]bool)
+
+	for _, r := range c.recent {
+		groups[c.group(r.stream)] = true
+	}
+
+	// A burst continues while activations keep arriving within the window
+	burst := len(groups) > c.limit || now.Before(c.burstUntil)
+
+	if burst {
+		c.burstUntil = now.Add(c.window)
+	}
+
+	var flagged []streamActivation
+
+	if burst {
+		for i := range c.recent {
+			if !c.recent[i].flagged {
+				c.recent[i].flagged = true
+				flagged = append(flagged, c.recent[i])
+			}
+		}
+	}
+
+	onBroadcast := c.OnBroadcast
+
+	c.lock.Unlock()
+
+	if onBroadcast != nil {
+		for _, f := range flagged {
+			f.activation.Broadcast = true
+
+			onBroadcast(f.stream, f.activation)
+		}
+	}
+
+	return burst
+}
@@ -0,0 +1,86 @@
+package precise
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBroadcastCoordinator(t *testing.T) {
+	clock := newFakeClock()
+
+	var reported []string
+
+	c := NewBroadcastCoordinator(WithBroadcastLimit(2), WithBroadcastWindow(time.Second), WithBroadcastFunc(func(stream string, a Activation) {
+		if !a.Broadcast {
+			t.Error("expected broadcast activations to be flagged")
+		}
+
+		reported = append(reported, stream)
+	}))
+	c.now = clock.Now
+
+	// Co-located streams count once, so three activations are two independent streams
+	c.CoLocate("kitchen", "kitchen-tablet")
+
+	for _, stream := range []string{"kitchen", "kitchen-tablet", "alice"} {
+		if c.report(stream, Activation{}) {
+			t.Fatalf("expected %s not to be part of a burst", stream)
+		}
+
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	// A third independent stream starts a burst, reporting the earlier activations
+	if !c.report("bob", Activation{}) {
+		t.Fatal("expected bob to start a burst")
+	}
+
+	if len(reported) != 4 || reported[0] != "kitchen" || reported[3] != "bob" {
+		t.Fatalf("expected all four activations to be reported, got %v", reported)
+	}
+
+	// Stragglers continue the burst, until the window passes without activations
+	clock.Advance(800 * time.Millisecond)
+
+	if !c.report("carol", Activation{}) {
+		t.Fatal("expected carol to be part of the burst")
+	}
+
+	clock.Advance(2 * time.Second)
+
+	if c.report("dave", Activation{}) {
+		t.Fatal("expected the burst to have ended")
+	}
+
+	if len(reported) != 5 {
+		t.Fatalf("expected 5 reported activations, got %d", len(reported))
+	}
+}
+
+func TestRunnerBroadcastSuppress(t *testing.T) {
+	clock := newFakeClock()
+
+	c := NewBroadcastCoordinator(WithBroadcastLimit(1), WithBroadcastAction(BroadcastSuppress))
+	c.now = clock.Now
+
+	var results []func() int
+
+	for _, stream := range []string{"alice", "bob", "carol"} {
+		r, res := newTriggeringRunner(t, WithBroadcastCoordinator(c, stream))
+
+		audio := modulatedTone(1, 48000)
+
+		for i := 0; i < len(audio); i += 320 {
+			r.Queue(audio[i : i+320])
+		}
+
+		results = append(results, res)
+	}
+
+	// The first stream activates, and the second is over the limit
+	for i, expected := range []int{1, 0, 0} {
+		if activations := results[i](); activations != expected {
+			t.Errorf("expected stream %d to have %d activations, got %d", i, expected, activations)
+		}
+	}
+}
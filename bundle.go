@@ -0,0 +1,452 @@
+package precise
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+var (
+	ErrNoBackend    = errors.New("no model in the bundle has a backend in this build")
+	ErrBundleDigest = errors.New("bundle file doesn't match its digest")
+)
+
+const (
+	// bundleManifest is the name of the manifest within a bundle, and bundleSignature its signature
+	bundleManifest  = "manifest.json"
+	bundleSignature = "manifest.json.sig"
+)
+
+// BundleManifest describes the contents of a model bundle
+type BundleManifest struct {
+	Keyword  string `json:"keyword"`
+	Version  string `json:"version"`
+	Language string `json:"language,omitempty"`
+	// Models maps backend names, such as tflite or onnx, to the model file for that backend
+	Models map[string]string `json:"models"`
+	// Params is the Params JSON file, which defaults to params.json
+	Params   string           `json:"params,omitempty"`
+	Detector DetectorDefaults `json:"detector"`
+	// Digests maps files in the bundle to their hex encoded SHA-256, so signing the manifest covers them
+	Digests map[string]string `json:"sha256,omitempty"`
+}
+
+// DetectorDefaults are the TriggerDetector settings a model was tuned with
+type DetectorDefaults struct {
+	Sensitivity  float32 `json:"sensitivity,omitempty"`
+	TriggerLevel int     `json:"trigger_level,omitempty"`
+}
+
+// Options returns the detector defaults as trigger options, leaving unset values at the detector defaults
+func (d DetectorDefaults) Options() []TriggerOption {
+	var opts []TriggerOption
+
+	if d.Sensitivity > 0 {
+		opts = append(opts, WithSensitivity(d.Sensitivity))
+	}
+
+	if d.TriggerLevel > 0 {
+		opts = append(opts, WithTriggerLevel(d.TriggerLevel))
+	}
+
+	return opts
+}
+
+type BundleOption func(*bundleOptions)
+
+type bundleOptions struct {
+	backends   []string
+	keys       TrustedKeys
+	verify     bool
+	signingKey ed25519.PrivateKey
+}
+
+// WithBackendPreference sets the order backends are chosen in, when the bundle has models for several
+func WithBackendPreference(backends ...string) BundleOption {
+	return func(o *bundleOptions) {
+		o.backends = backends
+	}
+}
+
+// WithTrustedKeys only reads bundles whose manifest is signed by one of the keys
+func WithTrustedKeys(keys TrustedKeys) BundleOption {
+	return func(o *bundleOptions) {
+		o.keys = keys
+		o.verify = true
+	}
+}
+
+// WithSigningKey signs the manifest of bundles written by WriteBundle
+func WithSigningKey(key ed25519.PrivateKey) BundleOption {
+	return func(o *bundleOptions) {
+		o.signingKey = key
+	}
+}
+
+// LoadBundle reads a bundle file, choosing a backend available in this build
+func LoadBundle(path string, opts ...BundleOption) (*Bundle, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ReadBundle(f, info.Size(), opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return b, nil
+}
+
+// ReadBundle reads a bundle, a zip file holding a manifest, the params and models
+func ReadBundle(r io.ReaderAt, size int64, opts ...BundleOption) (*Bundle, error) {
+	o := &bundleOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	z, err := zip.NewReader(r, size)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Params: NewParams()}
+
+	b.Manifest, err = readManifest(z, o.verify, o.keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paramsFile := b.Manifest.Params
+
+	if paramsFile == "" {
+		paramsFile = "params.json"
+	}
+
+	data, err := readBundleFile(z, b.Manifest, paramsFile, o.verify)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Params missing from the file keep their defaults
+	if err := json.Unmarshal(data, &b.Params); err != nil {
+		return nil, fmt.Errorf("%s: %w", paramsFile, err)
+	}
+
+	b.backend, err = chooseBackend(b.Manifest.Models, o.backends)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.model, err = readBundleFile(z, b.Manifest, b.Manifest.Models[b.backend.Name()], o.verify)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// VerifyBundle checks a bundle's manifest is signed by a trusted key, and every file it lists matches its digest
+func VerifyBundle(r io.ReaderAt, size int64, keys TrustedKeys) (BundleManifest, error) {
+	if len(keys) == 0 {
+		return BundleManifest{}, ErrNoTrustedKeys
+	}
+
+	z, err := zip.NewReader(r, size)
+
+	if err != nil {
+		return BundleManifest{}, err
+	}
+
+	manifest, err := readManifest(z, true, keys)
+
+	if err != nil {
+		return BundleManifest{}, err
+	}
+
+	for name := range manifest.Digests {
+		if _, err := readBundleFile(z, manifest, name, true); err != nil {
+			return BundleManifest{}, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// readManifest reads the manifest, verifying its signature against the trusted keys if verify is set
+func readManifest(z *zip.Reader, verify bool, keys TrustedKeys) (BundleManifest, error) {
+	var manifest BundleManifest
+
+	data, err := readZipFile(z, bundleManifest)
+
+	if err != nil {
+		return manifest, err
+	}
+
+	if verify {
+		sig, err := readZipFile(z, bundleSignature)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			return manifest, fmt.Errorf("%w: bundle isn't signed", ErrSignature)
+		} else if err != nil {
+			return manifest, err
+		}
+
+		if err := keys.Verify(data, sig); err != nil {
+			return manifest, fmt.Errorf("%s: %w", bundleManifest, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("%s: %w", bundleManifest, err)
+	}
+
+	return manifest, nil
+}
+
+// readBundleFile reads a file, checking it against its digest in the manifest.
+// Files in signed bundles must have a digest, or they aren't covered by the signature.
+func readBundleFile(z *zip.Reader, manifest BundleManifest, name string, signed bool) ([]byte, error) {
+	data, err := readZipFile(z, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expected, ok := manifest.Digests[name]
+
+	if !ok {
+		if signed {
+			return nil, fmt.Errorf("%w: %s has no digest in the manifest", ErrSignature, name)
+		}
+
+		return data, nil
+	}
+
+	if digest(data) != expected {
+		if signed {
+			return nil, fmt.Errorf("%w: %s doesn't match its digest", ErrSignature, name)
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrBundleDigest, name)
+	}
+
+	return data, nil
+}
+
+// chooseBackend returns the first backend in this build with a model, in order of preference
+func chooseBackend(models map[string]string, preference []string) (Backend, error) {
+	names := append(append([]string{}, preference...), Backends()...)
+
+	for _, name := range names {
+		if models[name] == "" {
+			continue
+		}
+
+		if backend, err := LookupBackend(name); err == nil {
+			return backend, nil
+		}
+	}
+
+	return nil, ErrNoBackend
+}
+
+func readZipFile(z *zip.Reader, name string) ([]byte, error) {
+	f, err := z.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// Bundle is a model with its params and detector defaults, read from a single file
+type Bundle struct {
+	Manifest BundleManifest
+	Params   Params
+	backend  Backend
+	model    []byte
+}
+
+// Backend returns the name of the backend chosen for the bundle
+func (b *Bundle) Backend() string {
+	return b.backend.Name()
+}
+
+// NewModel loads a new instance of the bundled model
+func (b *Bundle) NewModel() (Model, error) {
+	return b.backend.Load(b.model)
+}
+
+// NewListener creates a listener with a new instance of the bundled model.
+// Each stream needs its own listener, so this can be called once per stream.
+func (b *Bundle) NewListener(opts ...ListenerOption) (*Listener, error) {
+	model, err := b.NewModel()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListener(model, b.Params, opts...)
+}
+
+// TriggerOptions returns the detector defaults from the manifest, for WithDetectorOpts
+func (b *Bundle) TriggerOptions() []TriggerOption {
+	return b.Manifest.Detector.Options()
+}
+
+// WriteBundle writes a bundle holding the manifest, params and models, which map backend
+// names to model data. The manifest's Models, Params and Digests are filled in.
+func WriteBundle(w io.Writer, manifest BundleManifest, p Params, models map[string][]byte, opts ...BundleOption) error {
+	o := &bundleOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	z := zip.NewWriter(w)
+
+	manifest.Params = "params.json"
+	manifest.Models = make(map[string]string)
+	manifest.Digests = make(map[string]string)
+
+	for backend, data := range models {
+		name := "model." + backend
+
+		manifest.Models[backend] = name
+		manifest.Digests[name] = digest(data)
+
+		if err := writeZipFile(z, name, data); err != nil {
+			return err
+		}
+	}
+
+	params, err := json.MarshalIndent(p, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	manifest.Digests[manifest.Params] = digest(params)
+
+	if err := writeZipFile(z, manifest.Params, params); err != nil {
+		return err
+	}
+
+	if err := writeManifest(z, manifest, o.signingKey); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+// SignBundle copies a bundle to w with its manifest signed by key.
+// Digests are added for any files the manifest doesn't have them for.
+func SignBundle(w io.Writer, r io.ReaderAt, size int64, key ed25519.PrivateKey) error {
+	src, err := zip.NewReader(r, size)
+
+	if err != nil {
+		return err
+	}
+
+	manifest, err := readManifest(src, false, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if manifest.Digests == nil {
+		manifest.Digests = make(map[string]string)
+	}
+
+	z := zip.NewWriter(w)
+
+	for _, f := range src.File {
+		if f.Name == bundleManifest || f.Name == bundleSignature {
+			continue
+		}
+
+		if _, ok := manifest.Digests[f.Name]; !ok {
+			data, err := readZipFile(src, f.Name)
+
+			if err != nil {
+				return err
+			}
+
+			manifest.Digests[f.Name] = digest(data)
+		}
+
+		if err := z.Copy(f); err != nil {
+			return err
+		}
+	}
+
+	if err := writeManifest(z, manifest, key); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+// writeManifest writes the manifest, and its signature if there's a key
+func writeManifest(z *zip.Writer, manifest BundleManifest, key ed25519.PrivateKey) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeZipFile(z, bundleManifest, data); err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	return writeZipFile(z, bundleSignature, SignModel(key, data))
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func writeZipFile(z *zip.Writer, name string, data []byte) error {
+	f, err := z.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+
+	return err
+}
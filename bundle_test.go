@@ -0,0 +1,207 @@
+package precise
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeModel loads a model scoring the first byte of its data
+func fakeModel(data []byte) (Model, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty model")
+	}
+
+	return constantModel(float32(data[0]) / 255), nil
+}
+
+// fakeBackend loads models with fakeModel
+type fakeBackend struct{}
+
+func (fakeBackend) Name() string {
+	return "fake"
+}
+
+func (fakeBackend) Load(data []byte) (Model, error) {
+	return fakeModel(data)
+}
+
+func (fakeBackend) LoadFile(path string) (Model, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeModel(data)
+}
+
+// withFakeBackend replaces the backends in this build with a fake, which loads models scoring their first byte
+func withFakeBackend(t *testing.T) {
+	backendLock.Lock()
+	registered := backends
+	backends = []Backend{fakeBackend{}}
+	backendLock.Unlock()
+
+	t.Cleanup(func() {
+		backendLock.Lock()
+		backends = registered
+		backendLock.Unlock()
+	})
+}
+
+func TestBundle(t *testing.T) {
+	withFakeBackend(t)
+
+	p := NewParams()
+	p.ThresholdCenter = 0.3
+
+	var buf bytes.Buffer
+
+	err := WriteBundle(&buf, BundleManifest{
+		Keyword:  "hey astra",
+		Version:  "1.2.0",
+		Language: "en-US",
+		Detector: DetectorDefaults{Sensitivity: 0.6, TriggerLevel: 2},
+	}, p, map[string][]byte{
+		"fake":   {255},
+		"tflite": {0},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "astra.bundle")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBundle(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Manifest.Keyword != "hey astra" || b.Manifest.Version != "1.2.0" || b.Manifest.Language != "en-US" {
+		t.Errorf("expected the manifest to round trip, got %+v", b.Manifest)
+	}
+
+	if b.Params.ThresholdCenter != 0.3 || b.Params.SampleRate != 16000 {
+		t.Errorf("expected the bundled params, got %+v", b.Params)
+	}
+
+	// Only the fake backend is in this build
+	if b.Backend() != "fake" {
+		t.Errorf("expected the fake backend, got %s", b.Backend())
+	}
+
+	if len(b.TriggerOptions()) != 2 {
+		t.Errorf("expected 2 detector options, got %d", len(b.TriggerOptions()))
+	}
+
+	l, err := b.NewListener()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score, err := l.Score(nil); err != nil || score < 0.5 {
+		t.Errorf("expected the bundled model to score highly, got %.2f (%v)", score, err)
+	}
+}
+
+func TestBundleNoBackend(t *testing.T) {
+	withFakeBackend(t)
+
+	var buf bytes.Buffer
+
+	if err := WriteBundle(&buf, BundleManifest{Keyword: "hey astra"}, NewParams(), map[string][]byte{"coreml": {0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len())); !errors.Is(err, ErrNoBackend) {
+		t.Errorf("expected ErrNoBackend, got %v", err)
+	}
+}
+
+func TestBundleSignature(t *testing.T) {
+	withFakeBackend(t)
+
+	trusted, key := newSigningKey(t)
+	keys := TrustedKeys{trusted}
+
+	var unsigned bytes.Buffer
+
+	if err := WriteBundle(&unsigned, BundleManifest{Keyword: "hey astra"}, NewParams(), map[string][]byte{"fake": {255}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader(unsigned.Bytes()), int64(unsigned.Len()), WithTrustedKeys(keys)); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected an unsigned bundle to fail with ErrSignature, got %v", err)
+	}
+
+	// An empty key set must still require a signature
+	if _, err := ReadBundle(bytes.NewReader(unsigned.Bytes()), int64(unsigned.Len()), WithTrustedKeys(nil)); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected an unsigned bundle with no trusted keys to fail with ErrSignature, got %v", err)
+	}
+
+	if _, err := VerifyBundle(bytes.NewReader(unsigned.Bytes()), int64(unsigned.Len()), TrustedKeys{}); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("expected VerifyBundle with no trusted keys to fail with ErrNoTrustedKeys, got %v", err)
+	}
+
+	var signed bytes.Buffer
+
+	if err := SignBundle(&signed, bytes.NewReader(unsigned.Bytes()), int64(unsigned.Len()), key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader(signed.Bytes()), int64(signed.Len()), WithTrustedKeys(keys)); err != nil {
+		t.Errorf("expected a signed bundle to load, got %v", err)
+	}
+
+	_, untrusted := newSigningKey(t)
+
+	var other bytes.Buffer
+
+	if err := WriteBundle(&other, BundleManifest{Keyword: "hey astra"}, NewParams(), map[string][]byte{"fake": {255}}, WithSigningKey(untrusted)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifyBundle(bytes.NewReader(other.Bytes()), int64(other.Len()), keys); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected a bundle signed by an untrusted key to fail with ErrSignature, got %v", err)
+	}
+
+	// Replace the model, keeping the signed manifest
+	src, err := zip.NewReader(bytes.NewReader(signed.Bytes()), int64(signed.Len()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tampered bytes.Buffer
+
+	z := zip.NewWriter(&tampered)
+
+	for _, f := range src.File {
+		if f.Name == "model.fake" {
+			w, _ := z.Create(f.Name)
+			w.Write([]byte{254})
+			continue
+		}
+
+		if err := z.Copy(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	z.Close()
+
+	if _, err := ReadBundle(bytes.NewReader(tampered.Bytes()), int64(tampered.Len()), WithTrustedKeys(keys)); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected a tampered bundle to fail with ErrSignature, got %v", err)
+	}
+}
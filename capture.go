@@ -0,0 +1,256 @@
+package precise
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// CaptureEnd is the reason an utterance capture finished
+type CaptureEnd int
+
+const (
+	// EndSilence means the energy stayed below the silence threshold for the silence timeout,
+	// or with a VAD, that no speech was heard within the silence timeout
+	EndSilence CaptureEnd = iota
+	// EndSpeech means the VAD reported the end of the command
+	EndSpeech
+	// EndMaxDuration means the capture reached the maximum duration
+	EndMaxDuration
+	// EndClosed means the runner stopped during the capture
+	EndClosed
+)
+
+func (e CaptureEnd) String() string {
+	switch e {
+	case EndSilence:
+		return "Silence"
+	case EndSpeech:
+		return "Speech"
+	case EndMaxDuration:
+		return "MaxDuration"
+	case EndClosed:
+		return "Closed"
+	}
+	return ""
+}
+
+// Utterance is the audio captured following an activation
+type Utterance struct {
+	Samples    []int16
+	SampleRate int
+	Start      time.Time
+	Reason     CaptureEnd
+	// ctx is cancelled when the runner which captured the utterance is closed
+	ctx context.Context
+}
+
+// Context returns a context which is cancelled when the runner which captured the utterance is closed
+func (u Utterance) Context() context.Context {
+	if u.ctx == nil {
+		return context.Background()
+	}
+
+	return u.ctx
+}
+
+// Duration returns the length of the captured audio
+func (u Utterance) Duration() time.Duration {
+	return time.Duration(len(u.Samples)) * time.Second / time.Duration(u.SampleRate)
+}
+
+// UtteranceHandler receives captured utterances.
+// Handlers are called on their own goroutine, so they may block.
+type UtteranceHandler interface {
+	HandleUtterance(u Utterance)
+}
+
+// UtteranceHandlerFunc allows a func to be used as an UtteranceHandler
+type UtteranceHandlerFunc func(u Utterance)
+
+func (f UtteranceHandlerFunc) HandleUtterance(u Utterance) {
+	f(u)
+}
+
+// Transcriber converts an utterance to text, such as whisper.cpp or a cloud STT service
+type Transcriber interface {
+	Transcribe(ctx context.Context, u Utterance) (string, error)
+}
+
+type TranscriptFunc func(u Utterance, text string, err error)
+
+type TranscribeOption func(*transcribeOptions)
+
+type transcribeOptions struct {
+	timeout time.Duration
+}
+
+// WithTranscribeTimeout sets how long a transcription may take before its context is cancelled
+func WithTranscribeTimeout(d time.Duration) TranscribeOption {
+	return func(o *transcribeOptions) {
+		o.timeout = d
+	}
+}
+
+// TranscribeUtterances creates an UtteranceHandler passing utterances to a
+// Transcriber, and the result to f. Transcriptions are cancelled when they time
+// out, or the runner which captured the utterance is closed.
+func TranscribeUtterances(t Transcriber, f TranscriptFunc, opts ...TranscribeOption) UtteranceHandler {
+	o := &transcribeOptions{
+		timeout: 30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return UtteranceHandlerFunc(func(u Utterance) {
+		ctx, cancel := context.WithTimeout(u.Context(), o.timeout)
+		defer cancel()
+
+		text, err := t.Transcribe(ctx, u)
+
+		f(u, text, err)
+	})
+}
+
+type CaptureOption func(*utteranceCapture)
+
+// WithSilenceTimeout sets how long the audio must stay quiet to end a capture.
+// When the runner has a VAD, it's how long to wait for speech, and the VAD ends the capture after it.
+func WithSilenceTimeout(d time.Duration) CaptureOption {
+	return func(c *utteranceCapture) {
+		c.silenceTimeout = d
+	}
+}
+
+// WithSilenceThreshold sets the RMS level, from 0 to 1, below which audio is
+// considered silent
+func WithSilenceThreshold(rms float64) CaptureOption {
+	return func(c *utteranceCapture) {
+		c.silenceThreshold = rms
+	}
+}
+
+// WithMaxDuration sets the maximum length of a capture
+func WithMaxDuration(d time.Duration) CaptureOption {
+	return func(c *utteranceCapture) {
+		c.maxDuration = d
+	}
+}
+
+// newUtteranceCapture creates a capture with default settings
+func newUtteranceCapture(handler UtteranceHandler, opts ...CaptureOption) *utteranceCapture {
+	c := &utteranceCapture{
+		handler:          handler,
+		silenceTimeout:   time.Second,
+		silenceThreshold: 0.01,
+		maxDuration:      10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// utteranceCapture records audio after an activation until the end of speech
+type utteranceCapture struct {
+	handler          UtteranceHandler
+	ctx              context.Context
+	silenceTimeout   time.Duration
+	silenceThreshold float64
+	maxDuration      time.Duration
+	sampleRate       int
+	active           bool
+	start            time.Time
+	samples          []int16
+	silence          int
+	heard            bool
+}
+
+// begin starts a new capture
+func (c *utteranceCapture) begin(sampleRate int) {
+	c.active = true
+	c.sampleRate = sampleRate
+	c.start = time.Now()
+	c.samples = make([]int16, 0, int(c.maxDuration.Seconds()*float64(sampleRate)))
+	c.silence = 0
+	c.heard = false
+}
+
+// add appends samples to the capture, returning true when the capture is complete.
+// When gate is set, the end of speech is taken from its event instead of the audio energy.
+func (c *utteranceCapture) add(samples []int16, event SpeechEvent, gate *SpeechGate) (CaptureEnd, bool) {
+	c.samples = append(c.samples, samples...)
+
+	if len(c.samples) >= int(c.maxDuration.Seconds()*float64(c.sampleRate)) {
+		return EndMaxDuration, true
+	}
+
+	if gate != nil {
+		switch {
+		case event == CommandEnd:
+			return EndSpeech, true
+		case event == SpeechEnd:
+			// Speech which ends without being a command is the end of the wake word
+			c.heard = false
+		case gate.voiced:
+			c.heard = true
+		}
+
+		// The VAD only ends the command once it's heard, so wait for it to start
+		if !c.heard {
+			c.silence += len(samples)
+		}
+
+		return EndSilence, c.silence >= int(c.silenceTimeout.Seconds()*float64(c.sampleRate))
+	}
+
+	if rms(samples) < c.silenceThreshold {
+		c.silence += len(samples)
+	} else {
+		c.silence = 0
+	}
+
+	return EndSilence, c.silence >= int(c.silenceTimeout.Seconds()*float64(c.sampleRate))
+}
+
+// finish ends the capture and passes the utterance to the handler
+func (c *utteranceCapture) finish(reason CaptureEnd) {
+	u := Utterance{
+		Samples:    c.samples,
+		SampleRate: c.sampleRate,
+		Start:      c.start,
+		Reason:     reason,
+		ctx:        c.ctx,
+	}
+
+	c.active = false
+	c.samples = nil
+
+	go c.handler.HandleUtterance(u)
+}
+
+// cancel ends the capture without passing it to the handler
+func (c *utteranceCapture) cancel() {
+	c.active = false
+	c.samples = nil
+}
+
+// rms returns the root mean square of samples, from 0 to 1
+func rms(samples []int16) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	var sum float64
+
+	for _, s := range samples {
+		v := float64(s) * int16Divider
+		sum += v * v
+	}
+
+	return math.Sqrt(sum / float64(len(samples)))
+}
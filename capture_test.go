@@ -0,0 +1,225 @@
+package precise
+
+import (
+	"context"
+	"gorgonia.org/tensor"
+	"testing"
+	"time"
+)
+
+type fakeTranscriber struct{}
+
+func (fakeTranscriber) Transcribe(ctx context.Context, u Utterance) (string, error) {
+	return "play some music", nil
+}
+
+func TestRunnerCapture(t *testing.T) {
+	p := NewParams()
+
+	calls := 0
+
+	// Activate on the fourth chunk, then stay quiet
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls <= 4 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan string, 1)
+
+	var utterance Utterance
+
+	r := NewRunner(l, 1600,
+		WithCapture(TranscribeUtterances(fakeTranscriber{}, func(u Utterance, text string, err error) {
+			utterance = u
+			ch <- text
+		}), WithSilenceTimeout(300*time.Millisecond)),
+	)
+
+	defer r.Close()
+
+	loud := make([]int16, 1600)
+
+	for i := range loud {
+		loud[i] = 8000
+	}
+
+	for i := 0; i < 4; i++ {
+		r.Queue(loud)
+	}
+
+	// Command audio, followed by silence to end the capture
+	r.Queue(loud)
+	r.Queue(loud)
+
+	for i := 0; i < 3; i++ {
+		r.Queue(make([]int16, 1600))
+	}
+
+	select {
+	case text := <-ch:
+		if text != "play some music" {
+			t.Fatal("unexpected transcript", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("utterance was not captured")
+	}
+
+	if utterance.Reason != EndSilence {
+		t.Fatal("expected capture to end on silence, got", utterance.Reason)
+	}
+
+	if len(utterance.Samples) != 5*1600 {
+		t.Fatal("expected 5 chunks of audio, got", len(utterance.Samples))
+	}
+
+	if calls != 4 {
+		t.Fatal("expected inference to stop during capture, got", calls, "calls")
+	}
+}
+
+func TestRunnerCaptureVAD(t *testing.T) {
+	for name, c := range map[string]struct {
+		probs  []float32
+		reason CaptureEnd
+	}{
+		// The wake word, a pause, the command and silence
+		"command": {[]float32{1, 1, 1, 1, 0, 0, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, EndSpeech},
+		// The wake word, and nothing after it
+		"no speech": {[]float32{1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, EndSilence},
+	} {
+		calls := 0
+
+		// Activate at the end of the wake word
+		l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+			calls++
+
+			if calls <= 4 {
+				return 1, nil
+			}
+
+			return 0, nil
+		}), NewParams())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ch := make(chan Utterance, 1)
+
+		vad := &scriptedVAD{probs: c.probs}
+
+		r := NewRunner(l, 1600,
+			WithVAD(vad, WithMinSpeech(0), WithSpeechPadding(200*time.Millisecond)),
+			WithCapture(UtteranceHandlerFunc(func(u Utterance) {
+				ch <- u
+			}), WithSilenceTimeout(500*time.Millisecond)),
+		)
+
+		for i := 0; i < len(c.probs); i++ {
+			r.Queue(make([]int16, 1600))
+		}
+
+		select {
+		case u := <-ch:
+			if u.Reason != c.reason {
+				t.Errorf("%s: expected capture to end on %s, got %s", name, c.reason, u.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: utterance was not captured", name)
+		}
+
+		r.Close()
+	}
+}
+
+// blockingTranscriber waits for its context to end
+type blockingTranscriber struct{}
+
+func (blockingTranscriber) Transcribe(ctx context.Context, u Utterance) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestTranscribeUtterancesContext(t *testing.T) {
+	errs := make(chan error, 1)
+
+	handler := TranscribeUtterances(blockingTranscriber{}, func(u Utterance, text string, err error) {
+		errs <- err
+	}, WithTranscribeTimeout(50*time.Millisecond))
+
+	// Transcriptions time out
+	go handler.HandleUtterance(Utterance{})
+
+	select {
+	case err := <-errs:
+		if err != context.DeadlineExceeded {
+			t.Fatal("expected the transcription to time out, got", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("transcription did not time out")
+	}
+
+	// and are cancelled when the runner which captured the utterance is closed
+	calls := 0
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls <= 4 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(l, 1600, WithCapture(TranscribeUtterances(blockingTranscriber{}, func(u Utterance, text string, err error) {
+		errs <- err
+	}), WithMaxDuration(time.Second)))
+
+	for i := 0; i < 14; i++ {
+		r.Queue(make([]int16, 1600))
+	}
+
+	r.Close()
+
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Fatal("expected the transcription to be cancelled, got", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("transcription was not cancelled")
+	}
+}
+
+func TestCaptureMaxDuration(t *testing.T) {
+	c := newUtteranceCapture(nil, WithMaxDuration(200*time.Millisecond))
+	c.begin(16000)
+
+	loud := make([]int16, 1600)
+
+	for i := range loud {
+		loud[i] = 8000
+	}
+
+	if _, done := c.add(loud, SpeechNone, nil); done {
+		t.Fatal("capture ended early")
+	}
+
+	if reason, done := c.add(loud, SpeechNone, nil); !done || reason != EndMaxDuration {
+		t.Fatal("expected capture to end at max duration")
+	}
+}
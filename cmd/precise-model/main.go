@@ -0,0 +1,189 @@
+// Command precise-model manages signing keys, and signs and verifies models and bundles
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"github.com/tystuyfzand/precise-go"
+	"os"
+	"path/filepath"
+)
+
+const usage = `usage:
+  precise-model keygen <name>                       writes <name>.key and <name>.pub
+  precise-model sign -key <name.key> <file>...      signs models, or the manifest of bundles
+  precise-model verify -keys <trusted> <file>...    verifies models and bundles against trusted public keys
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(os.Args[2:])
+	case "sign":
+		err = sign(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// keygen writes a new private key, and the public key to add to trusted key files
+func keygen(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("keygen takes the name of the key")
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		return err
+	}
+
+	if err := precise.WriteSigningKey(args[0]+".key", private); err != nil {
+		return err
+	}
+
+	return os.WriteFile(args[0]+".pub", []byte(precise.EncodePublicKey(public)+"\n"), 0644)
+}
+
+func sign(args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := flags.String("key", "", "private key file")
+	flags.Parse(args)
+
+	key, err := precise.ReadSigningKey(*keyPath)
+
+	if err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args() {
+		bundle, err := isBundle(path)
+
+		if err != nil {
+			return err
+		}
+
+		if bundle {
+			err = signBundle(path, key)
+		} else {
+			err = precise.SignModelFile(path, key)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		fmt.Println("signed", path)
+	}
+
+	return nil
+}
+
+// signBundle replaces a bundle with a signed copy
+func signBundle(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".precise-bundle-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if err := precise.SignBundle(f, bytes.NewReader(data), int64(len(data)), key); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	keysPath := flags.String("keys", "", "trusted public keys file, one base64 key per line")
+	flags.Parse(args)
+
+	keys, err := precise.ReadTrustedKeys(*keysPath)
+
+	if err != nil {
+		return err
+	}
+
+	failed := false
+
+	for _, path := range flags.Args() {
+		bundle, err := isBundle(path)
+
+		if err == nil {
+			if bundle {
+				var data []byte
+
+				if data, err = os.ReadFile(path); err == nil {
+					_, err = precise.VerifyBundle(bytes.NewReader(data), int64(len(data)), keys)
+				}
+			} else {
+				_, err = keys.ReadFile(path)
+			}
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+
+		fmt.Println("verified", path)
+	}
+
+	if failed {
+		return fmt.Errorf("verification failed")
+	}
+
+	return nil
+}
+
+// isBundle returns whether a file is a zip file, and so a bundle rather than a model
+func isBundle(path string) (bool, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer f.Close()
+
+	magic := make([]byte, 4)
+
+	if _, err := f.Read(magic); err != nil {
+		return false, nil
+	}
+
+	return bytes.Equal(magic, []byte("PK\x03\x04")), nil
+}
@@ -0,0 +1,433 @@
+package precise
+
+import (
+	"sync"
+	"time"
+)
+
+// CompositePart is a keyword detection making up part of a composite activation
+type CompositePart struct {
+	Keyword    string
+	Activation Activation
+	// Start and End are the stream offsets of the keyword. Without keyword location, both are the time of the activation.
+	Start time.Duration
+	End   time.Duration
+}
+
+// CompositeActivation is emitted when a composite trigger matches
+type CompositeActivation struct {
+	Name  string
+	Parts []CompositePart
+	// Start and End are the stream offsets of the first and last parts
+	Start time.Duration
+	End   time.Duration
+}
+
+// CompositeFunc is called when a composite trigger matches
+type CompositeFunc func(a CompositeActivation)
+
+// Trigger is a condition on keyword detections, built with Keyword, Then, And and Unless
+type Trigger interface {
+	// matches returns the matches in the detections which are settled at the stream offset
+	matches(detections []keywordDetection, settled time.Duration) []compositeMatch
+	// span returns how long a match can take, to limit the history kept
+	span() time.Duration
+}
+
+// keywordDetection is a detection reported by a runner
+type keywordDetection struct {
+	id   int
+	part CompositePart
+}
+
+// compositeMatch is a set of detections matching a trigger
+type compositeMatch struct {
+	parts []keywordDetection
+	start time.Duration
+	end   time.Duration
+}
+
+// Keyword matches each detection of a keyword, named by WithComposer
+func Keyword(name string) Trigger {
+	return keywordTrigger(name)
+}
+
+type keywordTrigger string
+
+func (t keywordTrigger) matches(detections []keywordDetection, settled time.Duration) []compositeMatch {
+	var out []compositeMatch
+
+	for _, d := range detections {
+		if d.part.Keyword == string(t) {
+			out = append(out, compositeMatch{parts: []keywordDetection{d}, start: d.part.Start, end: d.part.End})
+		}
+	}
+
+	return out
+}
+
+func (t keywordTrigger) span() time.Duration {
+	return 0
+}
+
+// Then matches b starting after a ends, within the given time, such as "hey astra" then "stop".
+// Each b is matched with the latest a before it.
+func Then(a, b Trigger, within time.Duration) Trigger {
+	return &thenTrigger{a: a, b: b, within: within}
+}
+
+type thenTrigger struct {
+	a, b   Trigger
+	within time.Duration
+}
+
+func (t *thenTrigger) matches(detections []keywordDetection, settled time.Duration) []compositeMatch {
+	first := t.a.matches(detections, settled)
+
+	var out []compositeMatch
+
+	for _, mb := range t.b.matches(detections, settled) {
+		best := -1
+
+		for i, ma := range first {
+			if mb.start < ma.end || mb.end-ma.end > t.within || mb.end == ma.end {
+				continue
+			}
+
+			if best < 0 || ma.end > first[best].end {
+				best = i
+			}
+		}
+
+		if best >= 0 {
+			out = append(out, joinMatches(first[best], mb))
+		}
+	}
+
+	return out
+}
+
+func (t *thenTrigger) span() time.Duration {
+	return t.a.span() + t.b.span() + t.within
+}
+
+// And matches a and b in either order, ending within the given time of each other.
+// Each b is matched with the closest a.
+func And(a, b Trigger, within time.Duration) Trigger {
+	return &andTrigger{a: a, b: b, within: within}
+}
+
+type andTrigger struct {
+	a, b   Trigger
+	within time.Duration
+}
+
+func (t *andTrigger) matches(detections []keywordDetection, settled time.Duration) []compositeMatch {
+	first := t.a.matches(detections, settled)
+
+	var out []compositeMatch
+
+	for _, mb := range t.b.matches(detections, settled) {
+		best := -1
+		var bestDistance time.Duration
+
+		for i, ma := range first {
+			distance := absDuration(mb.end - ma.end)
+
+			if distance > t.within || sharesDetection(ma, mb) {
+				continue
+			}
+
+			if best < 0 || distance < bestDistance {
+				best = i
+				bestDistance = distance
+			}
+		}
+
+		if best >= 0 {
+			out = append(out, joinMatches(first[best], mb))
+		}
+	}
+
+	return out
+}
+
+func (t *andTrigger) span() time.Duration {
+	return t.a.span() + t.b.span() + t.within
+}
+
+// Unless matches a when b doesn't end within the given time of it, either before or after.
+// Matches are delayed until every runner in the composer has passed the end of the window,
+// except runners which have fallen far behind the others.
+func Unless(a, veto Trigger, within time.Duration) Trigger {
+	return &unlessTrigger{a: a, veto: veto, within: within}
+}
+
+type unlessTrigger struct {
+	a, veto Trigger
+	within  time.Duration
+}
+
+func (t *unlessTrigger) matches(detections []keywordDetection, settled time.Duration) []compositeMatch {
+	vetoes := t.veto.matches(detections, settled)
+
+	var out []compositeMatch
+
+	for _, ma := range t.a.matches(detections, settled) {
+		if settled < ma.end+t.within {
+			continue
+		}
+
+		vetoed := false
+
+		for _, mv := range vetoes {
+			if absDuration(mv.end-ma.end) <= t.within && !sharesDetection(ma, mv) {
+				vetoed = true
+				break
+			}
+		}
+
+		if !vetoed {
+			out = append(out, ma)
+		}
+	}
+
+	return out
+}
+
+func (t *unlessTrigger) span() time.Duration {
+	return t.a.span() + t.veto.span() + t.within
+}
+
+// joinMatches combines two matches, ordering the parts by time
+func joinMatches(a, b compositeMatch) compositeMatch {
+	if b.start < a.start {
+		a, b = b, a
+	}
+
+	m := compositeMatch{
+		parts: append(append([]keywordDetection{}, a.parts...), b.parts...),
+		start: a.start,
+		end:   a.end,
+	}
+
+	if b.end > m.end {
+		m.end = b.end
+	}
+
+	return m
+}
+
+// sharesDetection returns true if the matches have a detection in common
+func sharesDetection(a, b compositeMatch) bool {
+	for _, da := range a.parts {
+		for _, db := range b.parts {
+			if da.id == db.id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
+
+const (
+	// composerMaxLag is how far a runner may fall behind the others before the composer stops waiting for it,
+	// unless the composite triggers span longer
+	composerMaxLag = 5 * time.Second
+)
+
+// NewComposer creates a composer, which runners for each keyword join with WithComposer.
+// The runners must all process the same audio stream.
+func NewComposer(f CompositeFunc) *Composer {
+	return &Composer{
+		positions:   make(map[*Runner]time.Duration),
+		lock:        new(sync.Mutex),
+		OnComposite: f,
+	}
+}
+
+// Composer combines keyword detections from several runners, each with their own model,
+// into composite activations such as "hey astra, stop"
+type Composer struct {
+	composites []composite
+	detections []keywordDetection
+	nextID     int
+	// positions are the stream offsets each runner has processed up to
+	positions map[*Runner]time.Duration
+	lock      *sync.Mutex
+
+	OnComposite CompositeFunc
+}
+
+// composite is a named trigger, with the detections it has used
+type composite struct {
+	name    string
+	trigger Trigger
+	used    map[int]bool
+}
+
+// Add adds a composite trigger. Each detection is used in at most one activation of each composite.
+func (c *Composer) Add(name string, trigger Trigger) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.composites = append(c.composites, composite{name: name, trigger: trigger, used: make(map[int]bool)})
+}
+
+// register adds a runner, which the composer waits for before settling
+func (c *Composer) register(r *Runner) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.positions[r] = 0
+}
+
+// unregister removes a runner
+func (c *Composer) unregister(r *Runner) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.positions, r)
+}
+
+// report adds a detection of a keyword
+func (c *Composer) report(keyword string, a Activation) {
+	part := CompositePart{
+		Keyword:    keyword,
+		Activation: a,
+		Start:      a.Offset(),
+		End:        a.Offset(),
+	}
+
+	if a.Keyword.End > a.Keyword.Start {
+		part.Start = time.Duration(a.Keyword.Start) * time.Second / time.Duration(a.SampleRate)
+		part.End = time.Duration(a.Keyword.End) * time.Second / time.Duration(a.SampleRate)
+	}
+
+	c.lock.Lock()
+
+	c.detections = append(c.detections, keywordDetection{id: c.nextID, part: part})
+	c.nextID++
+
+	activations := c.evaluate()
+
+	c.lock.Unlock()
+
+	c.emit(activations)
+}
+
+// advance records that a runner has processed the stream up to offset
+func (c *Composer) advance(r *Runner, offset time.Duration) {
+	c.lock.Lock()
+
+	c.positions[r] = offset
+
+	var activations []CompositeActivation
+
+	// Only triggers waiting for the stream to pass a window need evaluating without new detections
+	if len(c.detections) > 0 {
+		activations = c.evaluate()
+	}
+
+	c.lock.Unlock()
+
+	c.emit(activations)
+}
+
+// evaluate finds new matches, and forgets detections too old to be part of one
+func (c *Composer) evaluate() []CompositeActivation {
+	var horizon time.Duration
+
+	for _, comp := range c.composites {
+		if span := comp.trigger.span(); span > horizon {
+			horizon = span
+		}
+	}
+
+	// Runners too far behind the leading runner, such as one which has stopped, aren't waited for
+	maxLag := 2 * horizon
+
+	if maxLag < composerMaxLag {
+		maxLag = composerMaxLag
+	}
+
+	var lead time.Duration
+
+	for _, position := range c.positions {
+		if position > lead {
+			lead = position
+		}
+	}
+
+	// The stream is settled up to the offset every runner still keeping up has reached
+	settled := time.Duration(-1)
+
+	for _, position := range c.positions {
+		if position < lead-maxLag {
+			continue
+		}
+
+		if settled < 0 || position < settled {
+			settled = position
+		}
+	}
+
+	var activations []CompositeActivation
+
+	for _, comp := range c.composites {
+		for _, m := range comp.trigger.matches(c.detections, settled) {
+			used := false
+
+			for _, d := range m.parts {
+				used = used || comp.used[d.id]
+			}
+
+			if used {
+				continue
+			}
+
+			a := CompositeActivation{Name: comp.name, Start: m.start, End: m.end}
+
+			for _, d := range m.parts {
+				comp.used[d.id] = true
+				a.Parts = append(a.Parts, d.part)
+			}
+
+			activations = append(activations, a)
+		}
+	}
+
+	// Keep detections which could still be part of a match, allowing for runners being up to a span apart
+	i := 0
+
+	for i < len(c.detections) && c.detections[i].part.End < settled-2*horizon {
+		for _, comp := range c.composites {
+			delete(comp.used, c.detections[i].id)
+		}
+
+		i++
+	}
+
+	c.detections = c.detections[i:]
+
+	return activations
+}
+
+func (c *Composer) emit(activations []CompositeActivation) {
+	if c.OnComposite == nil {
+		return
+	}
+
+	for _, a := range activations {
+		c.OnComposite(a)
+	}
+}
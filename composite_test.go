@@ -0,0 +1,189 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"sync"
+	"testing"
+	"time"
+)
+
+// detectionAt creates an activation at a stream offset
+func detectionAt(d time.Duration) Activation {
+	return Activation{SampleRate: 16000, Position: int64(d.Seconds() * 16000)}
+}
+
+func TestComposer(t *testing.T) {
+	var got []CompositeActivation
+
+	c := NewComposer(func(a CompositeActivation) {
+		got = append(got, a)
+	})
+
+	c.Add("stop", Then(Keyword("hey astra"), Keyword("stop"), 2*time.Second))
+	c.Add("both", And(Keyword("lights"), Keyword("on"), 500*time.Millisecond))
+	c.Add("hey astra", Unless(Keyword("hey astra"), Keyword("hey astral"), 300*time.Millisecond))
+
+	wake, command := &Runner{}, &Runner{}
+	c.register(wake)
+	c.register(command)
+
+	at := func(r *Runner, keyword string, d time.Duration) {
+		if keyword != "" {
+			c.report(keyword, detectionAt(d))
+		}
+
+		c.advance(r, d)
+	}
+
+	// "stop" only counts after "hey astra", and within the window
+	at(command, "stop", time.Second)
+	at(wake, "hey astra", 2*time.Second)
+	at(command, "stop", 3*time.Second)
+	at(wake, "", 3*time.Second)
+	at(command, "stop", 6*time.Second)
+	at(wake, "", 6*time.Second)
+
+	// "hey astral" vetoes "hey astra", even when detected after it
+	at(wake, "hey astra", 10*time.Second)
+	at(wake, "hey astral", 10200*time.Millisecond)
+	at(command, "", 11*time.Second)
+	at(wake, "", 11*time.Second)
+
+	// "lights" and "on" in either order
+	at(command, "on", 20*time.Second)
+	at(wake, "lights", 20300*time.Millisecond)
+	at(command, "", 21*time.Second)
+
+	names := make([]string, len(got))
+
+	for i, a := range got {
+		names[i] = a.Name
+	}
+
+	expected := []string{"stop", "hey astra", "both"}
+
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+
+	stop := got[0]
+
+	if len(stop.Parts) != 2 || stop.Parts[0].Keyword != "hey astra" || stop.Parts[1].End != 3*time.Second {
+		t.Errorf("expected hey astra then stop at 3s, got %+v", stop.Parts)
+	}
+
+	if stop.Start != 2*time.Second || stop.End != 3*time.Second {
+		t.Errorf("expected the activation from 2s to 3s, got %s to %s", stop.Start, stop.End)
+	}
+
+	// The unvetoed "hey astra" at 2s waits until both runners pass 2.3s
+	if got[1].End != 2*time.Second {
+		t.Errorf("expected hey astra at 2s, got %s", got[1].End)
+	}
+
+	if both := got[2]; both.Parts[0].Keyword != "on" || both.Parts[1].Keyword != "lights" {
+		t.Errorf("expected on then lights, got %+v", both.Parts)
+	}
+}
+
+func TestComposerStalledRunner(t *testing.T) {
+	var got []CompositeActivation
+
+	c := NewComposer(func(a CompositeActivation) {
+		got = append(got, a)
+	})
+
+	c.Add("hey astra", Unless(Keyword("hey astra"), Keyword("hey astral"), 300*time.Millisecond))
+
+	// The stalled runner never processes any audio
+	active, stalled := &Runner{}, &Runner{}
+	c.register(active)
+	c.register(stalled)
+
+	for i := 1; i <= 100; i++ {
+		d := time.Duration(i) * time.Second
+
+		c.report("hey astra", detectionAt(d))
+		c.advance(active, d)
+	}
+
+	// Once the stalled runner is left behind, every activation but the last, still in its window, is released
+	if len(got) != 99 {
+		t.Fatalf("expected 99 activations, got %d", len(got))
+	}
+
+	if len(c.detections) > 10 || len(c.composites[0].used) > 10 {
+		t.Fatalf("expected old detections to be forgotten, got %d detections and %d used", len(c.detections), len(c.composites[0].used))
+	}
+}
+
+func TestRunnerComposer(t *testing.T) {
+	lock := new(sync.Mutex)
+
+	var got []CompositeActivation
+
+	c := NewComposer(func(a CompositeActivation) {
+		lock.Lock()
+		got = append(got, a)
+		lock.Unlock()
+	})
+
+	c.Add("hey astra, stop", Then(Keyword("hey astra"), Keyword("stop"), 2*time.Second))
+
+	// Each model fires once, after its number of chunks
+	newRunner := func(keyword string, after int) *Runner {
+		calls := 0
+
+		l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+			calls++
+
+			if calls == after {
+				return 1, nil
+			}
+
+			return 0, nil
+		}), NewParams())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewRunner(l, 1600, WithComposer(c, keyword), WithDetectorOpts(WithTriggerLevel(0)))
+
+		t.Cleanup(func() {
+			r.Close()
+		})
+
+		return r
+	}
+
+	wake := newRunner("hey astra", 10)
+	command := newRunner("stop", 20)
+
+	chunk := make([]int16, 1600)
+
+	for i := 0; i < 30; i++ {
+		wake.Queue(chunk)
+		command.Queue(chunk)
+	}
+
+	wake.Queue(nil)
+	command.Queue(nil)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(got) != 1 {
+		t.Fatalf("expected a composite activation, got %d", len(got))
+	}
+
+	if got[0].Start != time.Second || got[0].End != 2*time.Second {
+		t.Errorf("expected the activation from 1s to 2s, got %s to %s", got[0].Start, got[0].End)
+	}
+}
@@ -0,0 +1,163 @@
+package precise
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown audio format")
+)
+
+// AudioStream is a stream of decoded audio, with interleaved samples from -1 to 1
+type AudioStream interface {
+	SampleRate() int
+	Channels() int
+	// ReadSamples reads interleaved samples into buf, returning io.EOF at the end of the stream.
+	// Only whole frames, with a sample for each channel, are returned.
+	ReadSamples(buf []float32) (int, error)
+}
+
+// Decoder decodes a single audio format
+type Decoder interface {
+	// Match returns true if the decoder can read a stream starting with header
+	Match(header []byte) bool
+	Decode(r io.Reader) (AudioStream, error)
+}
+
+// decoderHeaderSize is the number of bytes passed to Decoder.Match
+const decoderHeaderSize = 64
+
+var (
+	decoders    []Decoder
+	decoderLock sync.RWMutex
+)
+
+// RegisterDecoder adds a decoder. Decoders registered later take priority,
+// so a decoder can be replaced by registering another for the same format.
+func RegisterDecoder(d Decoder) {
+	decoderLock.Lock()
+	defer decoderLock.Unlock()
+
+	decoders = append([]Decoder{d}, decoders...)
+}
+
+// DecodeAudio detects the format of r and returns a stream of its audio
+func DecodeAudio(r io.Reader) (AudioStream, error) {
+	br := bufio.NewReader(r)
+
+	// Peek returns what is available for short streams, with an error we can ignore
+	header, _ := br.Peek(decoderHeaderSize)
+
+	decoderLock.RLock()
+	defer decoderLock.RUnlock()
+
+	for _, d := range decoders {
+		if d.Match(header) {
+			return d.Decode(br)
+		}
+	}
+
+	if isOggOpus(header) {
+		return nil, fmt.Errorf("%w: ogg opus requires a registered decoder", ErrUnsupportedEncoding)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// ReadAudioFile decodes an entire audio file of any registered format
+func ReadAudioFile(path string) (*Audio, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s, err := DecodeAudio(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadAllAudio(s)
+}
+
+// ReadAllAudio reads an AudioStream until the end
+func ReadAllAudio(s AudioStream) (*Audio, error) {
+	a := &Audio{
+		SampleRate: s.SampleRate(),
+		Channels:   s.Channels(),
+	}
+
+	buf := make([]float32, 4096*s.Channels())
+
+	for {
+		n, err := s.ReadSamples(buf)
+
+		a.Samples = append(a.Samples, buf[:n]...)
+
+		if err == io.EOF {
+			return a, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// NewStreamConverter creates a converter of s to the format described by p
+func NewStreamConverter(s AudioStream, p Params) (*StreamConverter, error) {
+	if p.SampleDepth != 2 {
+		return nil, fmt.Errorf("%w: %d byte samples, only 16-bit models are supported", ErrUnsupportedEncoding, p.SampleDepth)
+	}
+
+	if s.Channels() < 1 || s.SampleRate() < 1 {
+		return nil, fmt.Errorf("%w: %d channels at %dHz", ErrUnsupportedEncoding, s.Channels(), s.SampleRate())
+	}
+
+	return &StreamConverter{
+		stream:    s,
+		resampler: NewResampler(s.SampleRate(), p.SampleRate),
+		buf:       make([]float32, 4096*s.Channels()),
+	}, nil
+}
+
+// StreamConverter downmixes and resamples an AudioStream, one chunk at a time
+type StreamConverter struct {
+	stream    AudioStream
+	resampler *Resampler
+	buf       []float32
+	done      bool
+}
+
+// Next returns the next chunk of converted samples, or io.EOF at the end of the stream
+func (c *StreamConverter) Next() ([]int16, error) {
+	if c.done {
+		return nil, io.EOF
+	}
+
+	n, err := c.stream.ReadSamples(c.buf)
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	out := c.resampler.Process(downmix(c.buf[:n], c.stream.Channels()))
+
+	if err == io.EOF {
+		c.done = true
+		out = append(out, c.resampler.Flush()...)
+	}
+
+	return floatToInt16Slice(out), nil
+}
+
+// isOggOpus returns true if header is the start of an Ogg Opus stream
+func isOggOpus(header []byte) bool {
+	return len(header) >= 36 && string(header[0:4]) == "OggS" && string(header[28:36]) == "OpusHead"
+}
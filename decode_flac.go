@@ -0,0 +1,70 @@
+package precise
+
+import (
+	"github.com/mewkiz/flac"
+	"io"
+)
+
+func init() {
+	RegisterDecoder(flacDecoder{})
+}
+
+// flacDecoder is the Decoder for FLAC files
+type flacDecoder struct{}
+
+func (flacDecoder) Match(header []byte) bool {
+	return len(header) >= 4 && string(header[0:4]) == "fLaC"
+}
+
+func (flacDecoder) Decode(r io.Reader) (AudioStream, error) {
+	stream, err := flac.New(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &flacStream{
+		stream: stream,
+		scale:  1.0 / float32(int64(1)<<(stream.Info.BitsPerSample-1)),
+	}, nil
+}
+
+// flacStream interleaves decoded FLAC frames
+type flacStream struct {
+	stream  *flac.Stream
+	scale   float32
+	pending []float32
+}
+
+func (s *flacStream) SampleRate() int {
+	return int(s.stream.Info.SampleRate)
+}
+
+func (s *flacStream) Channels() int {
+	return int(s.stream.Info.NChannels)
+}
+
+func (s *flacStream) ReadSamples(buf []float32) (int, error) {
+	for len(s.pending) == 0 {
+		f, err := s.stream.ParseNext()
+
+		if err != nil {
+			return 0, err
+		}
+
+		channels := len(f.Subframes)
+
+		for i := 0; i < int(f.BlockSize); i++ {
+			for c := 0; c < channels; c++ {
+				s.pending = append(s.pending, float32(f.Subframes[c].Samples[i])*s.scale)
+			}
+		}
+	}
+
+	// Only copy whole frames
+	n := copy(buf[:len(buf)-len(buf)%s.Channels()], s.pending)
+
+	s.pending = s.pending[n:]
+
+	return n, nil
+}
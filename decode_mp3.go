@@ -0,0 +1,73 @@
+package precise
+
+import (
+	"encoding/binary"
+	"github.com/hajimehoshi/go-mp3"
+	"io"
+)
+
+func init() {
+	RegisterDecoder(mp3Decoder{})
+}
+
+// mp3Decoder is the Decoder for MP3 files, with or without an ID3 tag
+type mp3Decoder struct{}
+
+func (mp3Decoder) Match(header []byte) bool {
+	if len(header) >= 3 && string(header[0:3]) == "ID3" {
+		return true
+	}
+
+	// MPEG audio frame sync, with layer III
+	return len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x06 == 0x02
+}
+
+func (mp3Decoder) Decode(r io.Reader) (AudioStream, error) {
+	d, err := mp3.NewDecoder(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mp3Stream{
+		decoder: d,
+	}, nil
+}
+
+// mp3Stream converts the decoder's 16-bit stereo output to float samples
+type mp3Stream struct {
+	decoder *mp3.Decoder
+	buf     []byte
+}
+
+func (s *mp3Stream) SampleRate() int {
+	return s.decoder.SampleRate()
+}
+
+// Channels is always 2, the decoder outputs stereo for mono files
+func (s *mp3Stream) Channels() int {
+	return 2
+}
+
+func (s *mp3Stream) ReadSamples(buf []float32) (int, error) {
+	// Only read whole frames of two 16-bit samples
+	size := (len(buf) / 2) * 4
+
+	if len(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+
+	n, err := io.ReadFull(s.decoder, s.buf[:size])
+
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	samples := n / 4 * 2
+
+	for i := 0; i < samples; i++ {
+		buf[i] = float32(int16(binary.LittleEndian.Uint16(s.buf[i*2:]))) / 32768
+	}
+
+	return samples, err
+}
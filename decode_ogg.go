@@ -0,0 +1,48 @@
+package precise
+
+import (
+	"github.com/jfreymuth/oggvorbis"
+	"io"
+)
+
+func init() {
+	RegisterDecoder(oggVorbisDecoder{})
+}
+
+// oggVorbisDecoder is the Decoder for Ogg Vorbis files.
+// Ogg Opus has no pure Go decoder, but one can be added with RegisterDecoder.
+type oggVorbisDecoder struct{}
+
+func (oggVorbisDecoder) Match(header []byte) bool {
+	// The first packet of the first page is the vorbis identification header
+	return len(header) >= 35 && string(header[0:4]) == "OggS" && string(header[28:35]) == "\x01vorbis"
+}
+
+func (oggVorbisDecoder) Decode(r io.Reader) (AudioStream, error) {
+	reader, err := oggvorbis.NewReader(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &oggVorbisStream{
+		reader: reader,
+	}, nil
+}
+
+// oggVorbisStream wraps an oggvorbis.Reader, which already returns interleaved floats
+type oggVorbisStream struct {
+	reader *oggvorbis.Reader
+}
+
+func (s *oggVorbisStream) SampleRate() int {
+	return s.reader.SampleRate()
+}
+
+func (s *oggVorbisStream) Channels() int {
+	return s.reader.Channels()
+}
+
+func (s *oggVorbisStream) ReadSamples(buf []float32) (int, error) {
+	return s.reader.Read(buf[:len(buf)-len(buf)%s.Channels()])
+}
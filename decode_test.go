@@ -0,0 +1,108 @@
+package precise
+
+import (
+	"bytes"
+	"errors"
+	"gorgonia.org/tensor"
+	"io"
+	"testing"
+)
+
+func TestDecodeFormats(t *testing.T) {
+	for _, file := range []string{"testdata/sample.flac", "testdata/sample.ogg", "testdata/sample.mp3"} {
+		audio, err := ReadAudioFile(file)
+
+		if err != nil {
+			t.Fatal(file, err)
+		}
+
+		if audio.SampleRate == 0 || audio.Channels == 0 || len(audio.Samples) == 0 {
+			t.Fatal(file, "decoded no audio")
+		}
+
+		if len(audio.Samples)%audio.Channels != 0 {
+			t.Fatal(file, "decoded a partial frame")
+		}
+
+		t.Logf("%s: %d channels at %dHz, %.2fs", file, audio.Channels, audio.SampleRate, audio.Duration())
+	}
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	if _, err := DecodeAudio(bytes.NewReader([]byte("not audio at all"))); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatal("expected unknown format, got", err)
+	}
+
+	header := make([]byte, 64)
+	copy(header, "OggS")
+	copy(header[28:], "OpusHead")
+
+	if _, err := DecodeAudio(bytes.NewReader(header)); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatal("expected unsupported encoding for opus, got", err)
+	}
+}
+
+// rawDecoder decodes a made up format of 16-bit mono samples at 8kHz
+type rawDecoder struct{}
+
+func (rawDecoder) Match(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("RAW8"))
+}
+
+func (rawDecoder) Decode(r io.Reader) (AudioStream, error) {
+	if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+		return nil, err
+	}
+
+	return &WAVStream{
+		r: r,
+		decode: func(b []byte) float32 {
+			return float32(int16(b[0])|int16(b[1])<<8) / 32768
+		},
+		sampleRate: 8000,
+		channels:   1,
+		frameSize:  2,
+	}, nil
+}
+
+func TestProcessStream(t *testing.T) {
+	decoderLock.RLock()
+	registered := decoders
+	decoderLock.RUnlock()
+
+	t.Cleanup(func() {
+		decoderLock.Lock()
+		decoders = registered
+		decoderLock.Unlock()
+	})
+
+	RegisterDecoder(rawDecoder{})
+
+	// One second at 8kHz
+	data := append([]byte("RAW8"), make([]byte, 16000)...)
+
+	s, err := DecodeAudio(bytes.NewReader(data))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := l.ProcessStream(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One second resampled to 16kHz, in chunks of 1024 samples
+	if len(res.Predictions) != 16 {
+		t.Fatal("expected 16 predictions, got", len(res.Predictions))
+	}
+}
@@ -63,3 +63,8 @@ func (t *TriggerDetector) Update(prob float32) bool {
 
 	return false
 }
+
+// Reset clears the detection history
+func (t *TriggerDetector) Reset() {
+	t.activation = 0
+}
@@ -0,0 +1,237 @@
+package precise
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+const (
+	discordSampleRate = 48000
+	discordChannels   = 2
+	// discordFrameSize is the number of samples per channel in a 20ms frame
+	discordFrameSize = 960
+	// discordMaxFrameSize is the number of samples per channel in the longest (120ms) Opus frame
+	discordMaxFrameSize = 5760
+)
+
+// discordSilenceFrame is the Opus frame Discord sends after a user stops speaking
+var discordSilenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// OpusDecoder decodes Opus frames, such as a libopus decoder created for 48kHz stereo
+type OpusDecoder interface {
+	// Decode decodes frame into interleaved samples in pcm, returning the number of samples per channel
+	Decode(frame []byte, pcm []int16) (int, error)
+}
+
+// OpusDecoderFactory creates an OpusDecoder for a new speaker
+type OpusDecoderFactory func() (OpusDecoder, error)
+
+// VoicePacket is a received Discord voice packet
+type VoicePacket struct {
+	SSRC      uint32
+	Sequence  uint16
+	Timestamp uint32
+	Opus      []byte
+}
+
+type DiscordOption func(*DiscordReceiver)
+
+// WithDiscordJitterDepth sets how many packets may be waiting for a missing packet
+// before it is considered lost
+func WithDiscordJitterDepth(depth int) DiscordOption {
+	return func(d *DiscordReceiver) {
+		d.jitterDepth = depth
+	}
+}
+
+// WithDiscordMaxGap sets the longest gap between packets that is filled with silence, when the
+// runner's gap policy fills gaps. Longer gaps, such as a user pausing, reset the runner's feature window.
+func WithDiscordMaxGap(gap time.Duration) DiscordOption {
+	return func(d *DiscordReceiver) {
+		d.maxGap = gap
+	}
+}
+
+// NewDiscordReceiver creates a DiscordReceiver.
+// Each speaker's SSRC is fed to a separate Runner created by factory, with its own decoder from decoders.
+func NewDiscordReceiver(params Params, factory RunnerFactory, decoders OpusDecoderFactory, opts ...DiscordOption) *DiscordReceiver {
+	d := &DiscordReceiver{
+		params:      params,
+		factory:     factory,
+		decoders:    decoders,
+		jitterDepth: 5,
+		maxGap:      time.Second,
+		streams:     make(map[uint32]*rtpStream),
+		users:       make(map[uint32]string),
+		lock:        new(sync.Mutex),
+		userLock:    new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// DiscordReceiver routes Discord voice packets to a Runner per speaker
+type DiscordReceiver struct {
+	params      Params
+	factory     RunnerFactory
+	decoders    OpusDecoderFactory
+	jitterDepth int
+	maxGap      time.Duration
+	streams     map[uint32]*rtpStream
+	users       map[uint32]string
+	lock        *sync.Mutex
+	userLock    *sync.Mutex
+}
+
+// Handle decodes a voice packet and queues it on the speaker's runner, creating the runner if needed
+func (d *DiscordReceiver) Handle(p *VoicePacket) error {
+	d.lock.Lock()
+	stream, ok := d.streams[p.SSRC]
+	d.lock.Unlock()
+
+	if !ok {
+		// The runner is created without the lock, so a slow factory doesn't hold up other speakers
+		created, err := d.newStream(p.SSRC)
+
+		if err != nil {
+			return err
+		}
+
+		d.lock.Lock()
+
+		// Another packet from the speaker may have created their stream first
+		if stream, ok = d.streams[p.SSRC]; !ok {
+			stream = created
+			d.streams[p.SSRC] = stream
+		}
+
+		d.lock.Unlock()
+
+		if stream != created {
+			created.close()
+		}
+	}
+
+	// The payload is copied, as the packet may be held in the jitter buffer after the caller reuses it
+	stream.push(&RTPPacket{
+		Sequence:  p.Sequence,
+		Timestamp: p.Timestamp,
+		SSRC:      p.SSRC,
+		Payload:   append([]byte(nil), p.Opus...),
+	})
+
+	return nil
+}
+
+// SetSpeaking updates a user's speaking state, from Discord's speaking event.
+// When a user stops speaking, any packets still waiting for missing packets are queued.
+func (d *DiscordReceiver) SetSpeaking(userID string, ssrc uint32, speaking bool) {
+	d.userLock.Lock()
+	d.users[ssrc] = userID
+	d.userLock.Unlock()
+
+	if speaking {
+		return
+	}
+
+	d.lock.Lock()
+	stream, ok := d.streams[ssrc]
+	d.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	stream.push(nil)
+}
+
+// User returns the user ID of an SSRC, if a speaking event has been received for it.
+// It may be called from the RunnerFactory.
+func (d *DiscordReceiver) User(ssrc uint32) string {
+	d.userLock.Lock()
+	defer d.userLock.Unlock()
+
+	return d.users[ssrc]
+}
+
+// Remove closes the runner of an SSRC, such as when the user leaves the channel
+func (d *DiscordReceiver) Remove(ssrc uint32) {
+	d.lock.Lock()
+	stream, ok := d.streams[ssrc]
+	delete(d.streams, ssrc)
+	d.lock.Unlock()
+
+	if ok {
+		stream.close()
+	}
+
+	d.userLock.Lock()
+	delete(d.users, ssrc)
+	d.userLock.Unlock()
+}
+
+// Close closes every speaker's runner
+func (d *DiscordReceiver) Close() error {
+	d.lock.Lock()
+	streams := d.streams
+	d.streams = make(map[uint32]*rtpStream)
+	d.lock.Unlock()
+
+	for _, stream := range streams {
+		stream.close()
+	}
+
+	return nil
+}
+
+// newStream creates the stream of a new speaker
+func (d *DiscordReceiver) newStream(ssrc uint32) (*rtpStream, error) {
+	decoder, err := d.decoders()
+
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := d.factory(ssrc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pcm := make([]int16, discordMaxFrameSize*discordChannels)
+
+	stream := newRTPStream(runner, d.params.SampleRate, d.jitterDepth, d.maxGap, func(p *RTPPacket) ([]int16, int, error) {
+		// Silence frames are known to decode to silence
+		if isDiscordSilence(p.Payload) {
+			return make([]int16, discordFrameSize), discordSampleRate, nil
+		}
+
+		n, err := decoder.Decode(p.Payload, pcm)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		mono := make([]int16, n)
+
+		for i := range mono {
+			mono[i] = int16((int(pcm[i*2]) + int(pcm[i*2+1])) / 2)
+		}
+
+		return mono, discordSampleRate, nil
+	})
+
+	stream.start()
+
+	return stream, nil
+}
+
+// isDiscordSilence returns true if frame is one of the silence frames sent after speaking
+func isDiscordSilence(frame []byte) bool {
+	return bytes.Equal(frame, discordSilenceFrame)
+}
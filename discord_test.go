@@ -0,0 +1,311 @@
+package precise
+
+import (
+	"encoding/binary"
+	"errors"
+	"gorgonia.org/tensor"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOpusDecoder decodes a two byte level into a 20ms stereo frame
+type fakeOpusDecoder struct {
+	lock  *sync.Mutex
+	calls *int
+}
+
+func (d fakeOpusDecoder) Decode(frame []byte, pcm []int16) (int, error) {
+	d.lock.Lock()
+	*d.calls++
+	d.lock.Unlock()
+
+	if len(frame) != 2 {
+		return 0, errors.New("invalid frame")
+	}
+
+	level := int16(binary.BigEndian.Uint16(frame))
+
+	for i := 0; i < discordFrameSize; i++ {
+		pcm[i*2] = level + 100
+		pcm[i*2+1] = level - 100
+	}
+
+	return discordFrameSize, nil
+}
+
+func voiceFrame(level int16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(level))
+	return b
+}
+
+func TestDiscordReceiver(t *testing.T) {
+	lock := new(sync.Mutex)
+	runners := make(map[uint32]*Runner)
+	resets := make(map[uint32]int)
+	calls := 0
+
+	var receiver *DiscordReceiver
+
+	receiver = NewDiscordReceiver(NewParams(), func(id uint32) (*Runner, error) {
+		l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+			return 0, nil
+		}), NewParams())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if receiver.User(id) == "" {
+			t.Error("expected the speaking event before the first packet")
+		}
+
+		r := NewRunner(l, 320, WithPreRoll(10*time.Second), WithDiscontinuityFunc(func(d Discontinuity) {
+			if d.Reset {
+				lock.Lock()
+				resets[id]++
+				lock.Unlock()
+			}
+		}))
+
+		lock.Lock()
+		runners[id] = r
+		lock.Unlock()
+
+		return r, nil
+	}, func() (OpusDecoder, error) {
+		return fakeOpusDecoder{lock: lock, calls: &calls}, nil
+	})
+
+	receiver.SetSpeaking("alice", 1, true)
+	receiver.SetSpeaking("bob", 2, true)
+
+	// A recorded session: alice speaks twice with a pause between, and bob's packets arrive out of order
+	packets := []*VoicePacket{
+		{SSRC: 1, Sequence: 100, Timestamp: 0, Opus: voiceFrame(1000)},
+		{SSRC: 2, Sequence: 7, Timestamp: 9600, Opus: voiceFrame(-2000)},
+		{SSRC: 1, Sequence: 101, Timestamp: 960, Opus: voiceFrame(1000)},
+		{SSRC: 2, Sequence: 9, Timestamp: 11520, Opus: voiceFrame(-2000)},
+		{SSRC: 1, Sequence: 102, Timestamp: 1920, Opus: voiceFrame(1000)},
+		{SSRC: 2, Sequence: 8, Timestamp: 10560, Opus: voiceFrame(-2000)},
+		{SSRC: 1, Sequence: 103, Timestamp: 2880, Opus: discordSilenceFrame},
+		{SSRC: 1, Sequence: 104, Timestamp: 3840, Opus: discordSilenceFrame},
+		{SSRC: 1, Sequence: 105, Timestamp: 4800, Opus: discordSilenceFrame},
+		// Five seconds later
+		{SSRC: 1, Sequence: 106, Timestamp: 244800, Opus: voiceFrame(1000)},
+		{SSRC: 1, Sequence: 107, Timestamp: 245760, Opus: voiceFrame(1000)},
+		// Packet 11 is lost, and bob stops speaking
+		{SSRC: 2, Sequence: 10, Timestamp: 12480, Opus: voiceFrame(-2000)},
+		{SSRC: 2, Sequence: 12, Timestamp: 14400, Opus: voiceFrame(-2000)},
+	}
+
+	for _, p := range packets {
+		if err := receiver.Handle(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiver.SetSpeaking("bob", 2, false)
+
+	// Each 20ms frame is 320 samples at 16kHz, less the resampler's delay
+	for _, c := range []struct {
+		ssrc     uint32
+		resets   int
+		frames   int
+		level    int16
+		speaking []int
+	}{
+		// The pause after the silence frames resets the feature window, leaving only the speech after it
+		{1, 1, 2, 1000, []int{0, 1}},
+		// Speech with the lost frame filled with silence
+		{2, 0, 6, -2000, []int{0, 1, 2, 3, 5}},
+	} {
+		lock.Lock()
+		r := runners[c.ssrc]
+		lock.Unlock()
+
+		var audio []int16
+
+		deadline := time.Now().Add(2 * time.Second)
+
+		for time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			lock.Lock()
+			reset := resets[c.ssrc]
+			lock.Unlock()
+
+			audio = r.RecentAudio(10 * time.Second)
+
+			if reset == c.resets && len(audio) >= c.frames*320-32 {
+				break
+			}
+		}
+
+		lock.Lock()
+		reset := resets[c.ssrc]
+		lock.Unlock()
+
+		if reset != c.resets {
+			t.Fatalf("expected %d resets for %d, got %d", c.resets, c.ssrc, reset)
+		}
+
+		if len(audio) < c.frames*320-32 || len(audio) > c.frames*320 {
+			t.Fatalf("expected %d frames for %d, got %d samples", c.frames, c.ssrc, len(audio))
+		}
+
+		// Check the middle of each speech frame, allowing for the resampler's delay
+		for _, frame := range c.speaking {
+			if level := audio[frame*320+100]; level < c.level-50 || level > c.level+50 {
+				t.Errorf("expected level %d in frame %d of %d, got %d", c.level, frame, c.ssrc, level)
+			}
+		}
+	}
+
+	lock.Lock()
+	decoded := calls
+	lock.Unlock()
+
+	if decoded != 10 {
+		t.Fatal("expected silence frames to skip the decoder, got", decoded, "decodes")
+	}
+
+	if err := receiver.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscordReceiverStalledRunner(t *testing.T) {
+	lock := new(sync.Mutex)
+	calls := 0
+
+	receiver := NewDiscordReceiver(NewParams(), func(id uint32) (*Runner, error) {
+		l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+			return 0, nil
+		}), NewParams())
+
+		if err != nil {
+			return nil, err
+		}
+
+		// A closed runner never accepts samples
+		r := NewRunner(l, 320)
+		r.Close()
+
+		return r, nil
+	}, func() (OpusDecoder, error) {
+		return fakeOpusDecoder{lock: lock, calls: &calls}, nil
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for seq := 0; seq < streamQueueSize*2; seq++ {
+			if err := receiver.Handle(&VoicePacket{SSRC: 1, Sequence: uint16(seq), Timestamp: uint32(seq * 960), Opus: voiceFrame(1000)}); err != nil {
+				t.Error(err)
+			}
+		}
+
+		receiver.SetSpeaking("alice", 1, false)
+		receiver.Remove(1)
+		receiver.Close()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("receiver blocked on a stalled runner")
+	}
+}
+
+func TestDiscordReceiverSlowFactory(t *testing.T) {
+	lock := new(sync.Mutex)
+	runners := make(map[uint32]*Runner)
+	calls := 0
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	receiver := NewDiscordReceiver(NewParams(), func(id uint32) (*Runner, error) {
+		// Creating the first speaker's runner takes until the test releases it
+		if id == 1 {
+			close(entered)
+
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+		}
+
+		r, err := newSilentRunner()
+
+		if err != nil {
+			return nil, err
+		}
+
+		lock.Lock()
+		runners[id] = r
+		lock.Unlock()
+
+		return r, nil
+	}, func() (OpusDecoder, error) {
+		return fakeOpusDecoder{lock: lock, calls: &calls}, nil
+	})
+
+	defer receiver.Close()
+
+	slow := make(chan error)
+
+	go func() {
+		slow <- receiver.Handle(&VoicePacket{SSRC: 1, Opus: voiceFrame(1000)})
+	}()
+
+	<-entered
+
+	// The caller's buffer is reused for each packet, while later packets wait in the jitter buffer
+	handled := make(chan struct{})
+
+	go func() {
+		defer close(handled)
+
+		frame := make([]byte, 2)
+
+		for _, seq := range []uint16{0, 2, 1} {
+			copy(frame, voiceFrame(-2000))
+
+			if err := receiver.Handle(&VoicePacket{SSRC: 2, Sequence: seq, Timestamp: uint32(seq) * 960, Opus: frame}); err != nil {
+				t.Error(err)
+			}
+
+			copy(frame, voiceFrame(3000))
+		}
+	}()
+
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("a slow runner factory held up another speaker")
+	}
+
+	lock.Lock()
+	r := runners[2]
+	lock.Unlock()
+
+	audio := waitRecentAudio(t, r, 3*320-32)
+
+	// The held packet keeps the level it was received with
+	for frame := 0; frame < 3; frame++ {
+		if level := audio[frame*320+100]; level < -2050 || level > -1950 {
+			t.Fatalf("expected level -2000 in frame %d, got %d", frame, level)
+		}
+	}
+
+	close(release)
+
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,247 @@
+package precise
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// echoLevel is the RMS level above which playback is considered audible
+	echoLevel = 0.001
+	// echoHop is the envelope frame size used to compare audio with playback
+	echoHop = 10 * time.Millisecond
+	// echoAhead is how far playback may be written ahead of real time
+	echoAhead = 2 * time.Second
+)
+
+// EchoMode is how a Runner avoids activating on its own playback
+type EchoMode int
+
+const (
+	// EchoMute mutes detection during playback, and for the hold time after it
+	EchoMute EchoMode = iota
+	// EchoVeto keeps detecting during playback, but vetoes activations whose audio matches the playback
+	EchoVeto
+)
+
+type EchoOption func(*echoReference)
+
+// WithEchoHold sets how long detection stays muted after playback ends
+func WithEchoHold(d time.Duration) EchoOption {
+	return func(e *echoReference) {
+		e.hold = d
+	}
+}
+
+// WithEchoMaxDelay sets the longest delay between playback and its echo in the input
+func WithEchoMaxDelay(d time.Duration) EchoOption {
+	return func(e *echoReference) {
+		e.maxDelay = d
+	}
+}
+
+// WithEchoThreshold sets the correlation, from 0 to 1, above which an activation is vetoed
+func WithEchoThreshold(threshold float64) EchoOption {
+	return func(e *echoReference) {
+		e.threshold = threshold
+	}
+}
+
+// SelfTriggerFunc is called when an activation is vetoed for matching playback
+type SelfTriggerFunc func(a Activation, correlation float64)
+
+// newEchoReference creates the playback reference for a runner
+func newEchoReference(mode EchoMode, opts ...EchoOption) *echoReference {
+	e := &echoReference{
+		mode:      mode,
+		hold:      500 * time.Millisecond,
+		maxDelay:  500 * time.Millisecond,
+		threshold: 0.7,
+		lock:      new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// echoReference keeps recent playback, aligned to wall time
+type echoReference struct {
+	mode       EchoMode
+	hold       time.Duration
+	maxDelay   time.Duration
+	threshold  float64
+	sampleRate int
+	ring       *sampleRing
+	// end is the wall time the written playback ends, and audible the time the last audible playback ends
+	end     time.Time
+	audible time.Time
+	lock    *sync.Mutex
+}
+
+// init sizes the reference buffer for windows of audio up to window long
+func (e *echoReference) init(sampleRate int, window time.Duration) {
+	e.sampleRate = sampleRate
+	e.ring = newSampleRing(e.samples(window + e.maxDelay + echoAhead))
+}
+
+// write adds playback, which starts at now or where the previous playback ends
+func (e *echoReference) write(samples []int16, now time.Time) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// Silence between playback keeps the buffer aligned with wall time
+	if e.end.Before(now) {
+		if !e.end.IsZero() {
+			e.writeSilence(now.Sub(e.end))
+		}
+
+		e.end = now
+	}
+
+	e.ring.Write(samples)
+	e.end = e.end.Add(e.duration(len(samples)))
+
+	if rms(samples) > echoLevel {
+		e.audible = e.end
+	}
+}
+
+// writeSilence writes up to a full buffer of silence
+func (e *echoReference) writeSilence(d time.Duration) {
+	n := e.samples(d)
+
+	if n > len(e.ring.buf) {
+		n = len(e.ring.buf)
+	}
+
+	e.ring.Write(make([]int16, n))
+}
+
+// muted returns true if detection is muted by recent playback
+func (e *echoReference) muted(now time.Time) bool {
+	if e.mode != EchoMute {
+		return false
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return !e.audible.IsZero() && now.Before(e.audible.Add(e.hold))
+}
+
+// recent returns the n samples of playback ending at now, padded with silence
+func (e *echoReference) recent(n int, now time.Time) []int16 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	out := make([]int16, n)
+
+	if e.end.IsZero() {
+		return out
+	}
+
+	// Playback written ahead of now hasn't been heard yet
+	ahead := 0
+
+	if e.end.After(now) {
+		ahead = e.samples(e.end.Sub(now))
+	}
+
+	ref := e.ring.Last(n + ahead)
+
+	if ahead > len(ref) {
+		return out
+	}
+
+	ref = ref[:len(ref)-ahead]
+
+	// Playback which ended before now is followed by silence
+	if e.end.Before(now) {
+		gap := e.samples(now.Sub(e.end))
+
+		if gap >= n {
+			return out
+		}
+
+		if len(ref) > n-gap {
+			ref = ref[len(ref)-(n-gap):]
+		}
+
+		copy(out[n-gap-len(ref):], ref)
+
+		return out
+	}
+
+	copy(out[n-len(ref):], ref)
+
+	return out
+}
+
+// correlation returns how closely audio, which ends now, matches a delayed copy of the playback
+func (e *echoReference) correlation(audio []int16, now time.Time) float64 {
+	hop := e.samples(echoHop)
+	delay := e.samples(e.maxDelay) / hop
+
+	ref := e.recent(len(audio)+delay*hop, now)
+
+	mic := energyEnvelope(audio, hop)
+	refEnv := energyEnvelope(ref, hop)
+
+	var best float64
+
+	for lag := 0; lag <= delay; lag++ {
+		offset := delay - lag
+
+		if offset+len(mic) > len(refEnv) {
+			continue
+		}
+
+		if c := pearson(mic, refEnv[offset:offset+len(mic)]); c > best {
+			best = c
+		}
+	}
+
+	return best
+}
+
+func (e *echoReference) samples(d time.Duration) int {
+	return int(d.Seconds() * float64(e.sampleRate))
+}
+
+func (e *echoReference) duration(samples int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(e.sampleRate)
+}
+
+// pearson returns the correlation coefficient of a and b, or 0 if either is constant
+func pearson(a, b []float64) float64 {
+	var meanA, meanB float64
+
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+
+	var cov, varA, varB float64
+
+	for i := range a {
+		da := a[i] - meanA
+		db := b[i] - meanB
+
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,106 @@
+package precise
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// newEchoRunner creates a runner which activates after two seconds, counting activations and vetoes
+func newEchoRunner(t *testing.T, clock *fakeClock, opts ...Option) (*Runner, func() (int, int)) {
+	lock := new(sync.Mutex)
+	vetoes := 0
+
+	opts = append(opts, WithSelfTriggerFunc(func(a Activation, correlation float64) {
+		lock.Lock()
+		vetoes++
+		lock.Unlock()
+	}))
+
+	r, activations := newTriggeringRunner(t, opts...)
+	r.now = clock.Now
+
+	return r, func() (int, int) {
+		n := activations()
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		return n, vetoes
+	}
+}
+
+func TestEchoMute(t *testing.T) {
+	clock := newFakeClock()
+
+	r, results := newEchoRunner(t, clock, WithPlaybackReference(EchoMute, WithEchoHold(500*time.Millisecond)))
+
+	playback := modulatedTone(1, 48000)
+
+	// Three seconds of playback, heard by the microphone
+	for i := 0; i < len(playback); i += 320 {
+		r.Playback(playback[i : i+320])
+		r.Queue(playback[i : i+320])
+		clock.Advance(20 * time.Millisecond)
+	}
+
+	if activations, _ := results(); activations != 0 {
+		t.Fatal("expected no activations during playback, got", activations)
+	}
+
+	// Detection resumes after the hold time, with the model only called once unmuted
+	input := modulatedTone(2, 64000)
+
+	for i := 0; i < len(input); i += 320 {
+		r.Queue(input[i : i+320])
+		clock.Advance(20 * time.Millisecond)
+	}
+
+	if activations, _ := results(); activations != 1 {
+		t.Fatal("expected an activation after playback, got", activations)
+	}
+}
+
+func TestEchoVeto(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		input       []int16
+		activations int
+		vetoes      int
+	}{
+		// The playback echoes back 100ms later at half the level
+		{"echo", nil, 0, 1},
+		// A user speaks over the playback
+		{"barge in", modulatedTone(2, 48000), 1, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			clock := newFakeClock()
+
+			r, results := newEchoRunner(t, clock, WithPlaybackReference(EchoVeto))
+
+			playback := modulatedTone(1, 48000)
+
+			input := c.input
+
+			if input == nil {
+				input = make([]int16, 48000)
+
+				for i := 1600; i < len(input); i++ {
+					input[i] = playback[i-1600] / 2
+				}
+			}
+
+			for i := 0; i < len(playback); i += 320 {
+				r.Playback(playback[i : i+320])
+				r.Queue(input[i : i+320])
+				clock.Advance(20 * time.Millisecond)
+			}
+
+			activations, vetoes := results()
+
+			if activations != c.activations || vetoes != c.vetoes {
+				t.Fatalf("expected %d activations and %d vetoes, got %d and %d", c.activations, c.vetoes, activations, vetoes)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package precise
+
+import (
+	"errors"
+	"fmt"
+	"gorgonia.org/tensor"
+	"math"
+	"sync"
+)
+
+var (
+	ErrIncompatibleParams = errors.New("incompatible model params")
+	ErrNoMembers          = errors.New("ensemble has no members")
+)
+
+// FusionMode is how an Ensemble combines its members' scores
+type FusionMode int
+
+const (
+	// FuseMean averages the scores
+	FuseMean FusionMode = iota
+	// FuseMax takes the highest score
+	FuseMax
+	// FuseWeightedMean averages the scores with the weights from WithFusionWeights
+	FuseWeightedMean
+	// FuseLogistic combines the scores' log odds with learned weights and a bias, see WithLogisticWeights
+	FuseLogistic
+)
+
+func (m FusionMode) String() string {
+	switch m {
+	case FuseMean:
+		return "Mean"
+	case FuseMax:
+		return "Max"
+	case FuseWeightedMean:
+		return "WeightedMean"
+	case FuseLogistic:
+		return "Logistic"
+	}
+	return ""
+}
+
+// EnsembleMember is a model in an ensemble, with the params it was trained with
+type EnsembleMember struct {
+	Name   string
+	Model  Model
+	Params Params
+}
+
+// MemberScoreFunc is called after each prediction with the score of each member, in order, and the fused score
+type MemberScoreFunc func(scores []float32, fused float32)
+
+type EnsembleOption func(*Ensemble)
+
+// WithFusion sets how member scores are combined
+func WithFusion(mode FusionMode) EnsembleOption {
+	return func(e *Ensemble) {
+		e.mode = mode
+	}
+}
+
+// WithFusionWeights sets the weight of each member, in order, and selects FuseWeightedMean
+func WithFusionWeights(weights ...float32) EnsembleOption {
+	return func(e *Ensemble) {
+		e.mode = FuseWeightedMean
+		e.weights = weights
+	}
+}
+
+// WithLogisticWeights sets the learned bias and weight of each member, in order, and selects FuseLogistic.
+// The fused score is sigmoid(bias + sum(weight * logit(score))).
+func WithLogisticWeights(bias float32, weights ...float32) EnsembleOption {
+	return func(e *Ensemble) {
+		e.mode = FuseLogistic
+		e.bias = bias
+		e.weights = weights
+	}
+}
+
+// WithMemberScoreFunc sets a func called with the member scores after each prediction, for debugging
+func WithMemberScoreFunc(f MemberScoreFunc) EnsembleOption {
+	return func(e *Ensemble) {
+		e.OnScores = f
+	}
+}
+
+// NewEnsemble creates a Model combining the scores of several models, such as generations trained on different data.
+// The members must share the params which affect their features, and their threshold, as the fused
+// score is decoded with the first member's params, which Params returns.
+func NewEnsemble(members []EnsembleMember, opts ...EnsembleOption) (*Ensemble, error) {
+	if len(members) == 0 {
+		return nil, ErrNoMembers
+	}
+
+	for _, m := range members[1:] {
+		if err := CompatibleParams(members[0].Params, m.Params); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Name, err)
+		}
+
+		if !sameThreshold(members[0].Params, m.Params) {
+			return nil, fmt.Errorf("%s: %w: threshold differs", m.Name, ErrIncompatibleParams)
+		}
+	}
+
+	e := &Ensemble{
+		members: members,
+		scores:  make([]float32, len(members)),
+		lock:    new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if (e.mode == FuseWeightedMean || e.mode == FuseLogistic) && len(e.weights) != len(members) {
+		return nil, fmt.Errorf("%d weights for %d members", len(e.weights), len(members))
+	}
+
+	return e, nil
+}
+
+// Ensemble is a Model running several models on the same features and fusing their scores
+type Ensemble struct {
+	members []EnsembleMember
+	mode    FusionMode
+	weights []float32
+	bias    float32
+	scores  []float32
+	lock    *sync.Mutex
+
+	OnScores MemberScoreFunc
+}
+
+// Predict runs each member on the features, returning the fused score
+func (e *Ensemble) Predict(inputData tensor.Tensor) (float32, error) {
+	e.lock.Lock()
+
+	scores := make([]float32, len(e.members))
+
+	for i, m := range e.members {
+		score, err := m.Model.Predict(inputData)
+
+		if err != nil {
+			e.lock.Unlock()
+			return -1, fmt.Errorf("%s: %w", m.Name, err)
+		}
+
+		scores[i] = score
+	}
+
+	fused := e.fuse(scores)
+	e.scores = scores
+
+	e.lock.Unlock()
+
+	if e.OnScores != nil {
+		e.OnScores(scores, fused)
+	}
+
+	return fused, nil
+}
+
+// fuse combines member scores with the fusion mode
+func (e *Ensemble) fuse(scores []float32) float32 {
+	switch e.mode {
+	case FuseMax:
+		max := scores[0]
+
+		for _, s := range scores[1:] {
+			if s > max {
+				max = s
+			}
+		}
+
+		return max
+	case FuseWeightedMean:
+		var sum, total float32
+
+		for i, s := range scores {
+			sum += e.weights[i] * s
+			total += e.weights[i]
+		}
+
+		if total == 0 {
+			return 0
+		}
+
+		return sum / total
+	case FuseLogistic:
+		z := float64(e.bias)
+
+		for i, s := range scores {
+			z += float64(e.weights[i]) * logit(s)
+		}
+
+		return float32(1 / (1 + math.Exp(-z)))
+	}
+
+	var sum float32
+
+	for _, s := range scores {
+		sum += s
+	}
+
+	return sum / float32(len(scores))
+}
+
+// Scores returns each member's score from the last prediction, in order
+func (e *Ensemble) Scores() []float32 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return append([]float32{}, e.scores...)
+}
+
+// Members returns the names of the members, in order
+func (e *Ensemble) Members() []string {
+	names := make([]string, len(e.members))
+
+	for i, m := range e.members {
+		names[i] = m.Name
+	}
+
+	return names
+}
+
+// Params returns the params shared by the members, for creating a Listener
+func (e *Ensemble) Params() Params {
+	return e.members[0].Params
+}
+
+// Close closes every member, returning the first error
+func (e *Ensemble) Close() error {
+	var first error
+
+	for _, m := range e.members {
+		if err := m.Model.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// CompatibleParams returns an error if models with params a and b can't share features
+func CompatibleParams(a, b Params) error {
+	switch {
+	case a.SampleRate != b.SampleRate:
+		return fmt.Errorf("%w: sample rate %d and %d", ErrIncompatibleParams, a.SampleRate, b.SampleRate)
+	case a.WindowSamples() != b.WindowSamples() || a.HopSamples() != b.HopSamples():
+		return fmt.Errorf("%w: window or hop differs", ErrIncompatibleParams)
+	case a.BufferSamples() != b.BufferSamples():
+		return fmt.Errorf("%w: buffer %gs and %gs", ErrIncompatibleParams, a.BufferT, b.BufferT)
+	case a.NMFCC != b.NMFCC || a.NFilt != b.NFilt || a.NFft != b.NFft || a.UseDelta != b.UseDelta:
+		return fmt.Errorf("%w: MFCC settings differ", ErrIncompatibleParams)
+	case a.DCBlock != b.DCBlock || a.HighPass != b.HighPass || a.PreEmphasis != b.PreEmphasis:
+		return fmt.Errorf("%w: preprocessing differs", ErrIncompatibleParams)
+	}
+
+	return nil
+}
+
+// sameThreshold returns true if models with params a and b have their outputs decoded alike
+func sameThreshold(a, b Params) bool {
+	if a.ThresholdCenter != b.ThresholdCenter || len(a.ThresholdConfig) != len(b.ThresholdConfig) {
+		return false
+	}
+
+	for i := range a.ThresholdConfig {
+		if len(a.ThresholdConfig[i]) != len(b.ThresholdConfig[i]) {
+			return false
+		}
+
+		for j := range a.ThresholdConfig[i] {
+			if a.ThresholdConfig[i][j] != b.ThresholdConfig[i][j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// logit returns the log odds of a score, clamped away from 0 and 1
+func logit(s float32) float64 {
+	p := math.Min(math.Max(float64(s), 1e-6), 1-1e-6)
+
+	return math.Log(p / (1 - p))
+}
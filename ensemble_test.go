@@ -0,0 +1,90 @@
+package precise
+
+import (
+	"errors"
+	"gorgonia.org/tensor"
+	"math"
+	"testing"
+)
+
+// constantModel returns a model which always predicts score
+func constantModel(score float32) Model {
+	return funcModel(func(inputData tensor.Tensor) (float32, error) {
+		return score, nil
+	})
+}
+
+func TestEnsembleFusion(t *testing.T) {
+	members := []EnsembleMember{
+		{Name: "v1", Model: constantModel(0.2), Params: NewParams()},
+		{Name: "v2", Model: constantModel(0.8), Params: NewParams()},
+		{Name: "v3", Model: constantModel(0.5), Params: NewParams()},
+	}
+
+	for _, c := range []struct {
+		name     string
+		opts     []EnsembleOption
+		expected float32
+	}{
+		{"mean", nil, 0.5},
+		{"max", []EnsembleOption{WithFusion(FuseMax)}, 0.8},
+		{"weighted mean", []EnsembleOption{WithFusionWeights(1, 3, 0)}, 0.65},
+		// logit(0.8) is ln 4, so sigmoid(ln 4) is 0.8
+		{"logistic", []EnsembleOption{WithLogisticWeights(0, 0, 1, 5)}, 0.8},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var reported []float32
+
+			e, err := NewEnsemble(members, append(c.opts, WithMemberScoreFunc(func(scores []float32, fused float32) {
+				reported = scores
+			}))...)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fused, err := e.Predict(nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if math.Abs(float64(fused-c.expected)) > 1e-4 {
+				t.Errorf("expected %.2f, got %.4f", c.expected, fused)
+			}
+
+			if scores := e.Scores(); len(scores) != 3 || scores[1] != 0.8 || len(reported) != 3 {
+				t.Errorf("expected member scores, got %v and %v", scores, reported)
+			}
+		})
+	}
+}
+
+func TestEnsembleParams(t *testing.T) {
+	longer := NewParams()
+	longer.BufferT = 3
+
+	_, err := NewEnsemble([]EnsembleMember{
+		{Name: "v1", Model: constantModel(0), Params: NewParams()},
+		{Name: "v2", Model: constantModel(0), Params: longer},
+	})
+
+	if !errors.Is(err, ErrIncompatibleParams) {
+		t.Errorf("expected ErrIncompatibleParams, got %v", err)
+	}
+
+	// The fused score is decoded with one threshold, so the members' must match
+	tuned := NewParams()
+	tuned.ThresholdCenter = 0.3
+
+	if _, err := NewEnsemble([]EnsembleMember{
+		{Name: "v1", Model: constantModel(0), Params: NewParams()},
+		{Name: "v2", Model: constantModel(0), Params: tuned},
+	}); !errors.Is(err, ErrIncompatibleParams) {
+		t.Errorf("expected ErrIncompatibleParams for differing thresholds, got %v", err)
+	}
+
+	if _, err := NewEnsemble(nil); err != ErrNoMembers {
+		t.Errorf("expected ErrNoMembers, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package precise
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+var (
+	ErrNoClips = errors.New("no labelled clips found")
+)
+
+// LabelledClip is a clip of audio, labelled with whether it contains the wake word
+type LabelledClip struct {
+	Name     string
+	Samples  []int16
+	WakeWord bool
+}
+
+// ReadLabelledClips reads a dataset in the Precise layout, with wav files in
+// wake-word and not-wake-word directories, converting them to match p
+func ReadLabelledClips(dir string, p Params) ([]LabelledClip, error) {
+	var clips []LabelledClip
+
+	for _, label := range []struct {
+		dir      string
+		wakeWord bool
+	}{
+		{"wake-word", true},
+		{"not-wake-word", false},
+	} {
+		paths, err := filepath.Glob(filepath.Join(dir, label.dir, "*.wav"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			audio, err := ReadAudioFile(path)
+
+			if err != nil {
+				return nil, err
+			}
+
+			samples, err := ConvertAudio(audio, p)
+
+			if err != nil {
+				return nil, err
+			}
+
+			clips = append(clips, LabelledClip{
+				Name:     filepath.Join(label.dir, filepath.Base(path)),
+				Samples:  samples,
+				WakeWord: label.wakeWord,
+			})
+		}
+	}
+
+	if len(clips) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoClips, dir)
+	}
+
+	return clips, nil
+}
+
+// DetectionResults are the results of running a labelled set through a listener
+type DetectionResults struct {
+	WakeWords    int
+	NotWakeWords int
+	// FalseRejects are the wake word clips without an activation
+	FalseRejects []string
+	// FalseAccepts are the other clips with an activation
+	FalseAccepts []string
+}
+
+// FalseRejectRate returns the fraction of wake word clips which were missed
+func (r DetectionResults) FalseRejectRate() float64 {
+	if r.WakeWords == 0 {
+		return 0
+	}
+
+	return float64(len(r.FalseRejects)) / float64(r.WakeWords)
+}
+
+// FalseAcceptRate returns the fraction of other clips which activated
+func (r DetectionResults) FalseAcceptRate() float64 {
+	if r.NotWakeWords == 0 {
+		return 0
+	}
+
+	return float64(len(r.FalseAccepts)) / float64(r.NotWakeWords)
+}
+
+// Evaluate runs each clip through the model from a clean state, counting missed and false activations
+func (p *Listener) Evaluate(clips []LabelledClip, opts ...TriggerOption) (*DetectionResults, error) {
+	res := &DetectionResults{}
+
+	for _, clip := range clips {
+		fr, err := p.ProcessSamples(clip.Samples, opts...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		activated := len(fr.Activations) > 0
+
+		if clip.WakeWord {
+			res.WakeWords++
+
+			if !activated {
+				res.FalseRejects = append(res.FalseRejects, clip.Name)
+			}
+		} else {
+			res.NotWakeWords++
+
+			if activated {
+				res.FalseAccepts = append(res.FalseAccepts, clip.Name)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// ProcessorReport compares detection on a labelled set with and without a Processor
+type ProcessorReport struct {
+	Baseline  DetectionResults
+	Processed DetectionResults
+	// Fixed are the clips only detected correctly with the processor, and Broken those only detected correctly without it
+	Fixed  []string
+	Broken []string
+}
+
+// EvaluateProcessor reports how a Processor, such as a NoiseSuppressor, changes detection on a labelled set.
+// The model is not closed.
+func EvaluateProcessor(model Model, p Params, clips []LabelledClip, processor Processor, opts ...TriggerOption) (*ProcessorReport, error) {
+	baseline, err := NewListener(model, p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	processed, err := NewListener(model, p, WithProcessor(processor))
+
+	if err != nil {
+		return nil, err
+	}
+
+	baselineRes, err := baseline.Evaluate(clips, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	processedRes, err := processed.Evaluate(clips, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProcessorReport{
+		Baseline:  *baselineRes,
+		Processed: *processedRes,
+	}
+
+	before := incorrectClips(baselineRes)
+	after := incorrectClips(processedRes)
+
+	for _, clip := range clips {
+		switch {
+		case before[clip.Name] && !after[clip.Name]:
+			report.Fixed = append(report.Fixed, clip.Name)
+		case !before[clip.Name] && after[clip.Name]:
+			report.Broken = append(report.Broken, clip.Name)
+		}
+	}
+
+	return report, nil
+}
+
+// incorrectClips returns the set of clips with a false reject or accept
+func incorrectClips(r *DetectionResults) map[string]bool {
+	incorrect := make(map[string]bool)
+
+	for _, name := range r.FalseRejects {
+		incorrect[name] = true
+	}
+
+	for _, name := range r.FalseAccepts {
+		incorrect[name] = true
+	}
+
+	return incorrect
+}
@@ -0,0 +1,104 @@
+package precise
+
+import (
+	"errors"
+	"gorgonia.org/tensor"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLabelledClips(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := ReadLabelledClips(dir, NewParams()); !errors.Is(err, ErrNoClips) {
+		t.Fatal("expected no clips error, got", err)
+	}
+
+	for _, name := range []string{"wake-word/a.wav", "not-wake-word/b.wav"} {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Create(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := WriteWAV(f, sequence(0, 1600), 16000); err != nil {
+			t.Fatal(err)
+		}
+
+		f.Close()
+	}
+
+	clips, err := ReadLabelledClips(dir, NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(clips) != 2 || !clips[0].WakeWord || clips[1].WakeWord || len(clips[1].Samples) != 1600 {
+		t.Fatal("unexpected clips", clips)
+	}
+}
+
+func TestEvaluateProcessor(t *testing.T) {
+	// One second clips are 16 predictions, and four in a row activate
+	const predictions = 16
+
+	var outputs []float32
+
+	for _, activate := range []bool{
+		// Without the processor: a missed wake word, a correct rejection and a detected wake word
+		false, false, true,
+		// With the processor: the wake word is detected, but the other clip activates
+		true, true, true,
+	} {
+		for i := 0; i < predictions; i++ {
+			if activate && i >= 8 && i < 12 {
+				outputs = append(outputs, 1)
+			} else {
+				outputs = append(outputs, 0)
+			}
+		}
+	}
+
+	calls := 0
+
+	model := funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+		return outputs[calls-1], nil
+	})
+
+	clips := []LabelledClip{
+		{Name: "quiet", Samples: make([]int16, 16000), WakeWord: true},
+		{Name: "music", Samples: make([]int16, 16000)},
+		{Name: "clear", Samples: make([]int16, 16000), WakeWord: true},
+	}
+
+	report, err := EvaluateProcessor(model, NewParams(), clips, NewNoiseSuppressor(16000))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != len(outputs) {
+		t.Fatal("expected", len(outputs), "predictions, got", calls)
+	}
+
+	if report.Baseline.FalseRejectRate() != 0.5 || report.Baseline.FalseAcceptRate() != 0 {
+		t.Fatalf("unexpected baseline results %+v", report.Baseline)
+	}
+
+	if report.Processed.FalseRejectRate() != 0 || report.Processed.FalseAcceptRate() != 1 {
+		t.Fatalf("unexpected processed results %+v", report.Processed)
+	}
+
+	if len(report.Fixed) != 1 || report.Fixed[0] != "quiet" || len(report.Broken) != 1 || report.Broken[0] != "music" {
+		t.Fatalf("unexpected changes %+v", report)
+	}
+}
@@ -0,0 +1,120 @@
+package precise
+
+import "math"
+
+// dcBlockPole is the pole of the DC blocker, placing its cutoff at ~13Hz for 16kHz audio
+const dcBlockPole = 0.995
+
+// newPreFilter creates the preprocessing filters selected by p, or nil if there are none
+func newPreFilter(p Params) *preFilter {
+	if !p.DCBlock && p.HighPass <= 0 && p.PreEmphasis <= 0 {
+		return nil
+	}
+
+	f := &preFilter{
+		dcBlock:     p.DCBlock,
+		preEmphasis: float64(p.PreEmphasis),
+	}
+
+	if p.HighPass > 0 {
+		f.highPass = newHighPass(float64(p.HighPass), p.SampleRate)
+	}
+
+	return f
+}
+
+// preFilter applies the DC blocker, high-pass and pre-emphasis filters, in that order
+type preFilter struct {
+	dcBlock     bool
+	dcIn, dcOut float64
+	highPass    *biquad
+	preEmphasis float64
+	lastSample  float64
+}
+
+// Process filters samples, returning a new slice
+func (f *preFilter) Process(samples []int16) []int16 {
+	out := make([]int16, len(samples))
+
+	for i, s := range samples {
+		v := float64(s)
+
+		if f.dcBlock {
+			y := v - f.dcIn + dcBlockPole*f.dcOut
+			f.dcIn = v
+			f.dcOut = y
+			v = y
+		}
+
+		if f.highPass != nil {
+			v = f.highPass.process(v)
+		}
+
+		if f.preEmphasis > 0 {
+			y := v - f.preEmphasis*f.lastSample
+			f.lastSample = v
+			v = y
+		}
+
+		out[i] = int16(math.Max(math.Min(math.Round(v), math.MaxInt16), math.MinInt16))
+	}
+
+	return out
+}
+
+// Reset clears the filter history
+func (f *preFilter) Reset() {
+	f.dcIn, f.dcOut, f.lastSample = 0, 0, 0
+
+	if f.highPass != nil {
+		f.highPass.reset()
+	}
+}
+
+// newHighPass creates a second order Butterworth high-pass filter
+func newHighPass(cutoff float64, sampleRate int) *biquad {
+	w := 2 * math.Pi * cutoff / float64(sampleRate)
+	// sin(w) / 2Q, with a Q of 1/√2
+	alpha := math.Sin(w) / math.Sqrt2
+	cos := math.Cos(w)
+	a0 := 1 + alpha
+
+	return &biquad{
+		b0: (1 + cos) / 2 / a0,
+		b1: -(1 + cos) / a0,
+		b2: (1 + cos) / 2 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+// biquad is a second order IIR filter, in direct form I
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (b *biquad) process(x float64) float64 {
+	y := b.b0*x + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
+
+	b.x2, b.x1 = b.x1, x
+	b.y2, b.y1 = b.y1, y
+
+	return y
+}
+
+func (b *biquad) reset() {
+	b.x1, b.x2, b.y1, b.y2 = 0, 0, 0, 0
+}
+
+// Preprocess applies the preprocessing filters selected by p to a complete clip,
+// as the Listener does when streaming. This can be used to prepare training data.
+func (p Params) Preprocess(samples []int16) []int16 {
+	f := newPreFilter(p)
+
+	if f == nil {
+		return samples
+	}
+
+	return f.Process(samples)
+}
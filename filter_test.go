@@ -0,0 +1,82 @@
+package precise
+
+import (
+	"math"
+	"testing"
+)
+
+// tone creates n samples of a tone at freq Hz with the given amplitude, from 0 to 1
+func tone(freq, amplitude float64, n int) []int16 {
+	samples := make([]int16, n)
+
+	for i := range samples {
+		samples[i] = int16(amplitude * 32767 * math.Sin(2*math.Pi*freq*float64(i)/16000))
+	}
+
+	return samples
+}
+
+func TestPreprocessDCBlock(t *testing.T) {
+	p := NewParams()
+	p.DCBlock = true
+
+	input := tone(1000, 0.1, 16000)
+
+	for i := range input {
+		input[i] += 5000
+	}
+
+	out := p.Preprocess(input)
+
+	var sum float64
+
+	for _, s := range out[8000:] {
+		sum += float64(s)
+	}
+
+	if mean := sum / 8000; math.Abs(mean) > 10 {
+		t.Fatal("expected DC offset to be removed, mean is", mean)
+	}
+
+	if level := rms(out[8000:]); math.Abs(level-0.0707) > 0.003 {
+		t.Fatal("expected the tone to pass, got level", level)
+	}
+}
+
+func TestPreprocessHighPass(t *testing.T) {
+	p := NewParams()
+	p.HighPass = 200
+
+	// Two octaves below the cutoff is attenuated by 24dB
+	if level := rms(p.Preprocess(tone(50, 0.5, 16000))[8000:]); level > 0.03 {
+		t.Fatal("expected 50Hz to be attenuated, got level", level)
+	}
+
+	if level := rms(p.Preprocess(tone(1000, 0.5, 16000))[8000:]); math.Abs(level-0.3535) > 0.01 {
+		t.Fatal("expected 1kHz to pass, got level", level)
+	}
+}
+
+func TestPreprocessPreEmphasis(t *testing.T) {
+	p := NewParams()
+	p.PreEmphasis = 0.97
+
+	out := p.Preprocess([]int16{100, 100, 200, -100})
+	expected := []int16{100, 3, 103, -294}
+
+	if !equalSamples(out, expected) {
+		t.Fatal("expected", expected, "got", out)
+	}
+
+	// Streaming in chunks matches a single pass
+	f := newPreFilter(p)
+	streamed := append(f.Process([]int16{100, 100}), f.Process([]int16{200, -100})...)
+
+	if !equalSamples(streamed, expected) {
+		t.Fatal("expected streaming to match, got", streamed)
+	}
+
+	if newPreFilter(NewParams()) != nil {
+		t.Fatal("expected no filter by default")
+	}
+}
@@ -0,0 +1,191 @@
+package precise
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/yut-kt/goft"
+	"math"
+	"math/bits"
+)
+
+const (
+	// fingerprintFrameSize and fingerprintHop are in seconds, giving 1024 and 256 samples at 16kHz
+	fingerprintFrameSize = 0.064
+	fingerprintHop       = 0.016
+	// fingerprintBands is one more than the bits in each sub-fingerprint
+	fingerprintBands = 33
+	fingerprintLow   = 300.0
+	fingerprintHigh  = 3000.0
+	// fingerprintSilence is the RMS, in sample units, below which a frame is silent
+	fingerprintSilence = 8.0
+)
+
+// Fingerprint is a compact fingerprint of audio, with a 32-bit sub-fingerprint every 16ms.
+// Each bit is the sign of the change in energy difference between neighbouring bands,
+// which survives changes in level, codecs and mild noise. Silent frames are 0, and aren't compared.
+type Fingerprint []uint32
+
+// NewFingerprint creates the fingerprint of audio at sampleRate
+func NewFingerprint(audio []int16, sampleRate int) Fingerprint {
+	frameSize := 1
+
+	for frameSize < int(fingerprintFrameSize*float64(sampleRate)) {
+		frameSize <<= 1
+	}
+
+	hop := int(fingerprintHop * float64(sampleRate))
+
+	// Log spaced band edges, as FFT bins, with at least one bin per band
+	edges := make([]int, fingerprintBands+1)
+
+	for i := range edges {
+		freq := fingerprintLow * math.Pow(fingerprintHigh/fingerprintLow, float64(i)/fingerprintBands)
+		edges[i] = int(freq * float64(frameSize) / float64(sampleRate))
+
+		if i > 0 && edges[i] <= edges[i-1] {
+			edges[i] = edges[i-1] + 1
+		}
+	}
+
+	var fp Fingerprint
+	var previous []float64
+	var previousSilent bool
+
+	frame := make([]float64, frameSize)
+
+	for start := 0; start+frameSize <= len(audio); start += hop {
+		for i := range frame {
+			hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize))
+			frame[i] = float64(audio[start+i]) * hann
+		}
+
+		var power float64
+
+		for _, v := range audio[start : start+frameSize] {
+			power += float64(v) * float64(v)
+		}
+
+		// Silence, such as gaps filled with zeros, would match any other silence
+		silent := math.Sqrt(power/float64(frameSize)) < fingerprintSilence
+
+		spectrum, _ := goft.FFT(frame)
+
+		energy := make([]float64, fingerprintBands)
+
+		for b := range energy {
+			for k := edges[b]; k < edges[b+1]; k++ {
+				energy[b] += real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+			}
+		}
+
+		if previous != nil {
+			var sub uint32
+
+			for b := 0; b < fingerprintBands-1 && !silent && !previousSilent; b++ {
+				if energy[b]-energy[b+1]-(previous[b]-previous[b+1]) > 0 {
+					sub |= 1 << b
+				}
+			}
+
+			fp = append(fp, sub)
+		}
+
+		previous = energy
+		previousSilent = silent
+	}
+
+	return fp
+}
+
+// Similarity returns the best match between f and g, from 0 to 1, as the fraction of
+// matching bits in frames where neither is silent. Alignments are tried with at least half
+// of the shorter fingerprint's non-silent frames overlapping non-silent frames. Unrelated
+// audio is around 0.5, and silence doesn't match anything.
+func (f Fingerprint) Similarity(g Fingerprint) float64 {
+	short, long := f, g
+
+	if f.voiced() > g.voiced() {
+		short, long = g, f
+	}
+
+	minOverlap := (short.voiced() + 1) / 2
+
+	if minOverlap == 0 {
+		return 0
+	}
+
+	var best float64
+
+	for offset := 1 - len(short); offset < len(long); offset++ {
+		var differing, compared int
+
+		for i, sub := range short {
+			j := offset + i
+
+			if j < 0 || j >= len(long) || sub == 0 || long[j] == 0 {
+				continue
+			}
+
+			differing += bits.OnesCount32(sub ^ long[j])
+			compared++
+		}
+
+		if compared < minOverlap {
+			continue
+		}
+
+		if s := 1 - float64(differing)/float64(compared*32); s > best {
+			best = s
+		}
+	}
+
+	return best
+}
+
+// voiced returns the number of frames which aren't silent
+func (f Fingerprint) voiced() int {
+	n := 0
+
+	for _, sub := range f {
+		if sub != 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// MarshalJSON encodes the fingerprint as base64, to keep stored fingerprints compact
+func (f Fingerprint) MarshalJSON() ([]byte, error) {
+	b := make([]byte, len(f)*4)
+
+	for i, sub := range f {
+		binary.LittleEndian.PutUint32(b[i*4:], sub)
+	}
+
+	return json.Marshal(base64.StdEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a fingerprint encoded by MarshalJSON
+func (f *Fingerprint) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+
+	if err != nil {
+		return err
+	}
+
+	*f = make(Fingerprint, len(b)/4)
+
+	for i := range *f {
+		(*f)[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+
+	return nil
+}
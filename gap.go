@@ -0,0 +1,237 @@
+package precise
+
+import (
+	"math"
+	"time"
+)
+
+// driftWindow is how much audio is received before clock drift is measured
+const driftWindow = 10 * time.Second
+
+// GapPolicy is how a Runner handles audio which skips ahead
+type GapPolicy int
+
+const (
+	// GapFill inserts silence in place of the missing audio, up to the maximum fill.
+	// Longer gaps reset the feature window.
+	GapFill GapPolicy = iota
+	// GapReset resets the feature window, so audio either side of the gap is never combined
+	GapReset
+)
+
+// DiscontinuityKind is the kind of discontinuity found in a timestamped stream
+type DiscontinuityKind int
+
+const (
+	// DiscontinuityGap is audio which started after the end of the previous audio
+	DiscontinuityGap DiscontinuityKind = iota
+	// DiscontinuityOverlap is audio which started before the end of the previous audio
+	DiscontinuityOverlap
+	// DiscontinuityDrift is a sender clock running faster or slower than wall time
+	DiscontinuityDrift
+)
+
+func (k DiscontinuityKind) String() string {
+	switch k {
+	case DiscontinuityGap:
+		return "gap"
+	case DiscontinuityOverlap:
+		return "overlap"
+	case DiscontinuityDrift:
+		return "drift"
+	}
+
+	return "unknown"
+}
+
+// Discontinuity describes a discontinuity in a timestamped stream, and how it was handled
+type Discontinuity struct {
+	Kind DiscontinuityKind
+	// At is the sender's time the previous audio ended
+	At time.Duration
+	// Length is the duration of a gap or overlap
+	Length time.Duration
+	// Filled is the duration of silence inserted for a gap
+	Filled time.Duration
+	// Trimmed is the duration of repeated audio dropped for an overlap
+	Trimmed time.Duration
+	// Reset is true if the feature window was reset
+	Reset bool
+	// Drift is the difference between the sender's clock rate and wall time, such as 0.01 for 1% fast
+	Drift float64
+}
+
+type DiscontinuityFunc func(d Discontinuity)
+
+// queuedChunk is a chunk of samples waiting to be processed, with any discontinuities before it
+type queuedChunk struct {
+	samples         []int16
+	discontinuities []Discontinuity
+}
+
+// streamClock tracks the timestamps of audio passed to QueueAt
+type streamClock struct {
+	started bool
+	// next is the sender's time the previous audio ended
+	next time.Duration
+	// origin and originTime are the sender's time and wall time drift is measured from
+	origin     time.Duration
+	originTime time.Time
+	drifting   bool
+}
+
+// QueueAt passes in samples starting at the given time on the sender's clock, such as
+// a converted RTP timestamp. Gaps and overlaps with the previous samples are handled
+// by the gap policy, and reported to OnDiscontinuity with any clock drift.
+// QueueAt must not be called from multiple goroutines at once, and returns without queuing once the runner is closed.
+func (r *Runner) QueueAt(samples []int16, at time.Duration) {
+	r.queueAt(samples, at, r.maxGapFill)
+}
+
+// queueAt queues samples like QueueAt, filling gaps up to maxFill, such as a source's own limit
+func (r *Runner) queueAt(samples []int16, at time.Duration, maxFill time.Duration) {
+	now := r.now()
+
+	if !r.clock.started {
+		r.clock = streamClock{
+			started:    true,
+			next:       at + r.duration(len(samples)),
+			origin:     at,
+			originTime: now,
+		}
+
+		r.queue(queuedChunk{samples: samples})
+
+		return
+	}
+
+	var discontinuities []Discontinuity
+
+	end := at + r.duration(len(samples))
+	offset := at - r.clock.next
+
+	switch {
+	case offset > r.gapTolerance:
+		d := Discontinuity{
+			Kind:   DiscontinuityGap,
+			At:     r.clock.next,
+			Length: offset,
+		}
+
+		if r.gapPolicy == GapFill && offset <= maxFill {
+			d.Filled = offset
+			samples = append(make([]int16, r.samples(offset)), samples...)
+		} else {
+			d.Reset = true
+		}
+
+		discontinuities = append(discontinuities, d)
+	case -offset > r.maxGapFill:
+		// A large jump back is a restarted sender, rather than repeated audio
+		discontinuities = append(discontinuities, Discontinuity{
+			Kind:   DiscontinuityOverlap,
+			At:     r.clock.next,
+			Length: -offset,
+			Reset:  true,
+		})
+
+		r.clock.origin = at
+		r.clock.originTime = now
+	case -offset > r.gapTolerance:
+		d := Discontinuity{
+			Kind:   DiscontinuityOverlap,
+			At:     r.clock.next,
+			Length: -offset,
+		}
+
+		trim := r.samples(-offset)
+
+		if trim > len(samples) {
+			trim = len(samples)
+		}
+
+		d.Trimmed = r.duration(trim)
+		samples = samples[trim:]
+
+		if end < r.clock.next {
+			end = r.clock.next
+		}
+
+		discontinuities = append(discontinuities, d)
+	}
+
+	r.clock.next = end
+
+	if d, ok := r.checkDrift(at, now); ok {
+		discontinuities = append(discontinuities, d)
+	}
+
+	r.queue(queuedChunk{samples: samples, discontinuities: discontinuities})
+}
+
+// checkDrift compares the sender's clock to wall time, returning a discontinuity
+// when the drift first exceeds the tolerance
+func (r *Runner) checkDrift(at time.Duration, now time.Time) (Discontinuity, bool) {
+	elapsed := now.Sub(r.clock.originTime)
+
+	if elapsed < driftWindow {
+		return Discontinuity{}, false
+	}
+
+	drift := float64(at-r.clock.origin-elapsed) / float64(elapsed)
+
+	if math.Abs(drift) <= r.driftTolerance {
+		r.clock.drifting = false
+		return Discontinuity{}, false
+	}
+
+	if r.clock.drifting {
+		return Discontinuity{}, false
+	}
+
+	r.clock.drifting = true
+
+	return Discontinuity{
+		Kind:  DiscontinuityDrift,
+		At:    at,
+		Drift: drift,
+	}, true
+}
+
+// handleDiscontinuities resets the stream state if needed, and reports discontinuities
+func (r *Runner) handleDiscontinuities(discontinuities []Discontinuity) {
+	for _, d := range discontinuities {
+		if d.Reset {
+			r.listener.Reset()
+			r.detector.Reset()
+
+			if r.gate != nil {
+				r.gate.Reset()
+			}
+
+			// Audio before the discontinuity can't be joined to audio after it
+			if r.locator != nil {
+				r.locator.reset()
+			}
+
+			if r.recent != nil {
+				r.recent.Reset()
+			}
+
+			r.awaitCommand = false
+
+			if r.capture != nil && r.capture.active {
+				r.capture.cancel()
+			}
+		}
+
+		if r.OnDiscontinuity != nil {
+			r.OnDiscontinuity(d)
+		}
+	}
+}
+
+// duration converts a number of samples at the model sample rate to a duration
+func (r *Runner) duration(samples int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(r.listener.params.SampleRate)
+}
@@ -0,0 +1,203 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newGapRunner(t *testing.T, opts ...Option) (*Runner, func() []Discontinuity) {
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := new(sync.Mutex)
+
+	var discontinuities []Discontinuity
+
+	opts = append(opts, WithPreRoll(10*time.Second), WithDiscontinuityFunc(func(d Discontinuity) {
+		lock.Lock()
+		discontinuities = append(discontinuities, d)
+		lock.Unlock()
+	}))
+
+	r := NewRunner(l, 320, opts...)
+
+	t.Cleanup(func() {
+		r.Close()
+	})
+
+	return r, func() []Discontinuity {
+		// The runner only receives a chunk once the previous chunk has been processed
+		r.Queue(nil)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		return discontinuities
+	}
+}
+
+func TestQueueAtGaps(t *testing.T) {
+	r, discontinuities := newGapRunner(t)
+
+	const ms = time.Millisecond
+
+	r.QueueAt(sequence(1, 320), 0)
+	// A packet is lost
+	r.QueueAt(sequence(1001, 320), 40*ms)
+	// Small timestamp jitter is ignored
+	r.QueueAt(sequence(2001, 320), 62*ms)
+	// The previous 10ms is repeated
+	r.QueueAt(sequence(3001, 320), 72*ms)
+
+	discontinuities()
+
+	audio := r.RecentAudio(10 * time.Second)
+
+	var want []int16
+	want = append(want, sequence(1, 320)...)
+	want = append(want, make([]int16, 320)...)
+	want = append(want, sequence(1001, 320)...)
+	want = append(want, sequence(2001, 320)...)
+	want = append(want, sequence(3161, 160)...)
+
+	if !equalSamples(audio, want) {
+		t.Fatal("unexpected audio after handling gaps")
+	}
+
+	// The sender was silent for too long to fill
+	r.QueueAt(sequence(4001, 320), 5*time.Second)
+
+	got := discontinuities()
+
+	expected := []Discontinuity{
+		{Kind: DiscontinuityGap, At: 20 * ms, Length: 20 * ms, Filled: 20 * ms},
+		{Kind: DiscontinuityOverlap, At: 82 * ms, Length: 10 * ms, Trimmed: 10 * ms},
+		{Kind: DiscontinuityGap, At: 92 * ms, Length: 5*time.Second - 92*ms, Reset: true},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatal("expected 3 discontinuities, got", got)
+	}
+
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], got[i])
+		}
+	}
+
+	// Audio before a reset isn't joined to audio after it
+	if audio := r.RecentAudio(10 * time.Second); !equalSamples(audio, sequence(4001, 320)) {
+		t.Fatal("expected only the audio after the reset, got", len(audio), "samples")
+	}
+}
+
+func TestQueueAtResetPolicy(t *testing.T) {
+	r, discontinuities := newGapRunner(t, WithGapPolicy(GapReset))
+
+	r.QueueAt(sequence(1, 320), 0)
+	r.QueueAt(sequence(1, 320), 40*time.Millisecond)
+
+	got := discontinuities()
+
+	if len(got) != 1 || !got[0].Reset || got[0].Filled != 0 {
+		t.Fatal("expected a reset without fill, got", got)
+	}
+
+	if audio := r.RecentAudio(10 * time.Second); len(audio) != 320 {
+		t.Fatal("expected no silence to be inserted, got", len(audio), "samples")
+	}
+}
+
+func TestQueueAtDrift(t *testing.T) {
+	r, discontinuities := newGapRunner(t)
+
+	wall := time.Now()
+
+	r.now = func() time.Time {
+		return wall
+	}
+
+	// The sender's clock runs 4% fast, which is within the gap tolerance for each packet
+	for i := 0; i < 1000; i++ {
+		r.QueueAt(make([]int16, 320), time.Duration(i)*20800*time.Microsecond)
+		wall = wall.Add(20 * time.Millisecond)
+	}
+
+	got := discontinuities()
+
+	var drift []Discontinuity
+
+	for _, d := range got {
+		if d.Kind == DiscontinuityDrift {
+			drift = append(drift, d)
+		} else if d.Reset {
+			t.Fatal("unexpected reset", d)
+		}
+	}
+
+	if len(drift) != 1 {
+		t.Fatal("expected drift to be reported once, got", drift)
+	}
+
+	if math.Abs(drift[0].Drift-0.04) > 0.001 {
+		t.Fatal("expected 4% drift, got", drift[0].Drift)
+	}
+}
+
+func TestQueueAtResetCapture(t *testing.T) {
+	calls := 0
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls <= 4 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	utterances := make(chan Utterance, 1)
+
+	r := NewRunner(l, 320, WithKeywordLocation(), WithCapture(UtteranceHandlerFunc(func(u Utterance) {
+		utterances <- u
+	})))
+
+	defer r.Close()
+
+	for i := 0; i < 5; i++ {
+		r.QueueAt(make([]int16, 320), time.Duration(i)*20*time.Millisecond)
+	}
+
+	r.Queue(nil)
+
+	if !r.capture.active || len(r.locator.history) == 0 {
+		t.Fatal("expected the activation to start a capture")
+	}
+
+	// The sender restarts during the command
+	r.QueueAt(make([]int16, 320), 10*time.Second)
+	r.Queue(nil)
+
+	if r.capture.active || len(r.locator.history) > 1 || len(r.RecentAudio(time.Second)) != 320 {
+		t.Fatal("expected the reset to cancel the capture and forget earlier audio")
+	}
+
+	select {
+	case <-utterances:
+		t.Fatal("expected the capture to be cancelled without an utterance")
+	default:
+	}
+}
@@ -5,16 +5,18 @@ go 1.19
 replace github.com/ivansuteja96/go-onnxruntime => github.com/tystuyfzand/go-onnxruntime v0.0.0-20230107221602-893efd995fc3
 
 require (
-	github.com/cryptix/wav v0.0.0-20180415113528-8bdace674401
+	github.com/hajimehoshi/go-mp3 v0.3.4
 	github.com/ivansuteja96/go-onnxruntime v0.0.0-20220819143618-84b1a0db69d3
+	github.com/jfreymuth/oggvorbis v1.0.5
 	github.com/mattn/go-tflite v1.0.4
+	github.com/mewkiz/flac v1.0.12
+	github.com/yut-kt/goft v0.1.0
 	github.com/yut-kt/gomfcc v0.0.0-20220503093809-d81a01b6bf53
 	gorgonia.org/tensor v0.9.24
 )
 
 require (
 	github.com/apache/arrow/go/arrow v0.0.0-20201229220542-30ce2eb5d4dc // indirect
-	github.com/cheekybits/is v0.0.0-20150225183255-68e9c0620927 // indirect
 	github.com/chewxy/hm v1.0.0 // indirect
 	github.com/chewxy/math32 v1.0.8 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -22,17 +24,16 @@ require (
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/flatbuffers v1.12.0 // indirect
 	github.com/google/go-cmp v0.5.6 // indirect
+	github.com/icza/bitio v1.1.0 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	github.com/mattn/go-pointer v0.0.1 // indirect
+	github.com/mewkiz/pkg v0.0.0-20230226050401-4010bf0fec14 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/xtgo/set v1.0.0 // indirect
-	github.com/yut-kt/goft v0.1.0 // indirect
 	github.com/yut-kt/gowindow v0.1.7 // indirect
 	go4.org/unsafe/assume-no-moving-gc v0.0.0-20220617031537-928513b29760 // indirect
 	golang.org/x/exp v0.0.0-20200224162631-6cc2880d07d6 // indirect
-	golang.org/x/net v0.0.0-20210917221730-978cfadd31cf // indirect
-	golang.org/x/sys v0.0.0-20210917161153-d61c044b1678 // indirect
-	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gonum.org/v1/gonum v0.9.0 // indirect
 	google.golang.org/genproto v0.0.0-20210917145530-b395a37504d4 // indirect
@@ -0,0 +1,93 @@
+package precise
+
+// newJitterBuffer creates a jitter buffer holding up to depth out of order packets
+func newJitterBuffer(depth int) *jitterBuffer {
+	return &jitterBuffer{
+		depth:   depth,
+		packets: make(map[uint16]*RTPPacket),
+	}
+}
+
+// jitterBuffer reorders packets by sequence number.
+// Packets are held until the missing packets before them arrive, or until more
+// than depth packets are waiting, at which point the missing packets are skipped.
+type jitterBuffer struct {
+	depth   int
+	packets map[uint16]*RTPPacket
+	next    uint16
+	started bool
+}
+
+// push adds a packet, returning any packets which are now ready, in order
+func (j *jitterBuffer) push(p *RTPPacket) []*RTPPacket {
+	if !j.started {
+		j.next = p.Sequence
+		j.started = true
+	}
+
+	// Drop late and duplicate packets
+	if seqBefore(p.Sequence, j.next) {
+		return nil
+	}
+
+	if _, ok := j.packets[p.Sequence]; ok {
+		return nil
+	}
+
+	j.packets[p.Sequence] = p
+
+	var ready []*RTPPacket
+
+	for {
+		if p, ok := j.packets[j.next]; ok {
+			delete(j.packets, j.next)
+			ready = append(ready, p)
+			j.next++
+			continue
+		}
+
+		if len(j.packets) <= j.depth {
+			break
+		}
+
+		// Give up on the missing packet, and skip to the oldest we have
+		j.next = j.oldest()
+	}
+
+	return ready
+}
+
+// flush returns all waiting packets in order, skipping any that are missing
+func (j *jitterBuffer) flush() []*RTPPacket {
+	var ready []*RTPPacket
+
+	for len(j.packets) > 0 {
+		j.next = j.oldest()
+		ready = append(ready, j.packets[j.next])
+		delete(j.packets, j.next)
+		j.next++
+	}
+
+	return ready
+}
+
+// oldest returns the earliest sequence number in the buffer
+func (j *jitterBuffer) oldest() uint16 {
+	first := true
+
+	var oldest uint16
+
+	for seq := range j.packets {
+		if first || seqBefore(seq, oldest) {
+			oldest = seq
+			first = false
+		}
+	}
+
+	return oldest
+}
+
+// seqBefore compares sequence numbers, allowing for wrap around
+func seqBefore(a, b uint16) bool {
+	return int16(a-b) < 0
+}
@@ -3,18 +3,25 @@ package precise
 import (
 	"errors"
 	"gorgonia.org/tensor"
+	"sync"
 )
 
 var (
 	ErrModelClosed = errors.New("model closed")
 )
 
-func NewListener(model Model, p Params) (*Listener, error) {
+func NewListener(model Model, p Params, opts ...ListenerOption) (*Listener, error) {
 	l := &Listener{
 		params:      p,
 		model:       model,
 		windowAudio: make([]int16, 0),
 		mfccs:       tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(p.NFeatures(), p.NMFCC)),
+		filter:      newPreFilter(p),
+		lock:        new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(l)
 	}
 
 	config := DefaultThreshold
@@ -30,9 +37,20 @@ type Listener struct {
 	windowAudio []int16
 	mfccs       *tensor.Dense
 	decoder     *ThresholdDecoder
+	filter      *preFilter
+	processors  []Processor
+	lock        *sync.Mutex
 }
 
 func (p *Listener) updateVectors(audio []int16) tensor.Tensor {
+	if p.filter != nil {
+		audio = p.filter.Process(audio)
+	}
+
+	for _, processor := range p.processors {
+		audio = processor.Process(audio)
+	}
+
 	p.windowAudio = append(p.windowAudio, audio...)
 
 	if len(p.windowAudio) >= p.params.WindowSamples() {
@@ -68,14 +86,55 @@ func (p *Listener) updateVectors(audio []int16) tensor.Tensor {
 }
 
 func (p *Listener) Update(audio []int16) (float32, error) {
+	prob, _, err := p.update(audio)
+
+	return prob, err
+}
+
+// update adds audio to the feature window and runs the model, also returning the features it was run on
+func (p *Listener) update(audio []int16) (float32, tensor.Tensor, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	if p.model == nil {
-		return -1, ErrModelClosed
+		return -1, nil, ErrModelClosed
 	}
 
 	mfccs := p.updateVectors(audio)
 
 	rawOutput, err := p.model.Predict(mfccs)
 
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return p.decoder.Decode(rawOutput), mfccs, nil
+}
+
+// Score runs the model on the last BufferT of audio, padding with silence if
+// needed. This does not affect the streaming feature window, and processors
+// are not applied, as their state belongs to the stream. Preprocessing filters
+// from Params are applied from a clean state.
+func (p *Listener) Score(audio []int16) (float32, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.model == nil {
+		return -1, ErrModelClosed
+	}
+
+	bufferSamples := p.params.BufferSamples()
+
+	if len(audio) < bufferSamples {
+		padded := make([]int16, bufferSamples)
+		copy(padded[bufferSamples-len(audio):], audio)
+		audio = padded
+	} else {
+		audio = audio[len(audio)-bufferSamples:]
+	}
+
+	rawOutput, err := p.model.Predict(mfccSpec(p.params.Preprocess(audio), p.params))
+
 	if err != nil {
 		return -1, err
 	}
@@ -83,7 +142,61 @@ func (p *Listener) Update(audio []int16) (float32, error) {
 	return p.decoder.Decode(rawOutput), nil
 }
 
+// SetModel replaces the model between predictions, keeping the feature window, so no audio is lost.
+// The old model is closed once any prediction using it has finished.
+func (p *Listener) SetModel(model Model) error {
+	if err := CheckInputShape(model, p.params); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+
+	if p.model == nil {
+		p.lock.Unlock()
+		return ErrModelClosed
+	}
+
+	old := p.model
+	p.model = model
+
+	p.lock.Unlock()
+
+	return old.Close()
+}
+
+// Reset clears the feature window, as if the listener was new
+func (p *Listener) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.windowAudio = p.windowAudio[:0]
+	p.mfccs = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(p.params.NFeatures(), p.params.NMFCC))
+
+	if p.filter != nil {
+		p.filter.Reset()
+	}
+
+	for _, processor := range p.processors {
+		processor.Reset()
+	}
+}
+
+// Skip adds audio to the feature window without running the model
+func (p *Listener) Skip(audio []int16) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.updateVectors(audio)
+}
+
 func (p *Listener) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.model == nil {
+		return nil
+	}
+
 	err := p.model.Close()
 
 	if err != nil {
@@ -0,0 +1,204 @@
+package precise
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Segment is a span of a stream in samples, counted from the first sample the runner received
+type Segment struct {
+	Start int64
+	End   int64
+}
+
+// Len returns the number of samples in the segment
+func (s Segment) Len() int {
+	return int(s.End - s.Start)
+}
+
+type LocatorOption func(*keywordLocator)
+
+// WithLocatorRefinement re-scores windows ending at shifted positions with the
+// model to refine the end of the keyword. This runs the model once per hop of
+// the keyword at activation time.
+func WithLocatorRefinement() LocatorOption {
+	return func(k *keywordLocator) {
+		k.refine = true
+	}
+}
+
+// WithLocatorGap sets the longest pause allowed inside the keyword
+func WithLocatorGap(d time.Duration) LocatorOption {
+	return func(k *keywordLocator) {
+		k.maxGap = d
+	}
+}
+
+// probPoint is a single prediction, and the stream position of the end of its chunk
+type probPoint struct {
+	end  int64
+	prob float32
+}
+
+// newKeywordLocator creates a new locator for the given params
+func newKeywordLocator(params Params, opts ...LocatorOption) *keywordLocator {
+	k := &keywordLocator{
+		params: params,
+		maxGap: 150 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	return k
+}
+
+// keywordLocator estimates the position of the keyword within the audio
+// preceding an activation, using the probability curve and energy envelope
+type keywordLocator struct {
+	params  Params
+	maxGap  time.Duration
+	refine  bool
+	history []probPoint
+}
+
+// add records a prediction, dropping predictions older than two buffer lengths
+func (k *keywordLocator) add(end int64, prob float32) {
+	k.history = append(k.history, probPoint{end: end, prob: prob})
+
+	oldest := end - int64(2*k.params.BufferSamples())
+
+	i := 0
+
+	for i < len(k.history) && k.history[i].end < oldest {
+		i++
+	}
+
+	k.history = k.history[i:]
+}
+
+// reset forgets the predictions, such as after a discontinuity in the stream
+func (k *keywordLocator) reset() {
+	k.history = nil
+}
+
+// locate returns the estimated keyword segment in audio, which ends at position.
+// Predictions above threshold are considered part of the activation.
+func (k *keywordLocator) locate(audio []int16, position int64, threshold float32) Segment {
+	offset := position - int64(len(audio))
+	hop := k.params.HopSamples()
+
+	// The output rises once the end of the keyword is inside the window,
+	// so the first prediction of the run leading to the activation bounds the end
+	end := position
+
+	for i := len(k.history) - 1; i >= 0 && k.history[i].prob > threshold; i-- {
+		end = k.history[i].end
+	}
+
+	fallback := Segment{Start: end - int64(k.params.BufferSamples()), End: end}
+
+	if fallback.Start < offset {
+		fallback.Start = offset
+	}
+
+	env := energyEnvelope(audio, hop)
+
+	if len(env) == 0 {
+		return fallback
+	}
+
+	level := speechLevel(env)
+
+	endHop := int((end - offset + int64(hop) - 1) / int64(hop))
+
+	if endHop > len(env) {
+		endHop = len(env)
+	}
+
+	// Trim any quiet hops between the end of the keyword and the prediction
+	for endHop > 0 && env[endHop-1] < level {
+		endHop--
+	}
+
+	if endHop == 0 {
+		return fallback
+	}
+
+	// Walk back through the speech, allowing short pauses, up to a buffer length
+	limit := endHop - k.params.BufferSamples()/hop
+
+	if limit < 0 {
+		limit = 0
+	}
+
+	maxGap := int(k.maxGap.Seconds()*float64(k.params.SampleRate)) / hop
+	startHop := endHop - 1
+	gap := 0
+
+	for i := endHop - 1; i >= limit; i-- {
+		if env[i] >= level {
+			startHop = i
+			gap = 0
+		} else if gap++; gap > maxGap {
+			break
+		}
+	}
+
+	return Segment{
+		Start: offset + int64(startHop*hop),
+		End:   offset + int64(endHop*hop),
+	}
+}
+
+// refineEnd re-scores windows ending at each hop of the segment, returning a
+// segment ending at the first window the model accepts
+func (k *keywordLocator) refineEnd(l *Listener, audio []int16, position int64, segment Segment, threshold float32) (Segment, error) {
+	offset := position - int64(len(audio))
+	hop := int64(k.params.HopSamples())
+
+	for end := segment.Start + hop; end <= position; end += hop {
+		prob, err := l.Score(audio[:end-offset])
+
+		if err != nil {
+			return segment, err
+		}
+
+		if prob > threshold {
+			segment.End = end
+			break
+		}
+	}
+
+	return segment, nil
+}
+
+// energyEnvelope returns the RMS level of each hop of audio
+func energyEnvelope(audio []int16, hop int) []float64 {
+	env := make([]float64, 0, len(audio)/hop+1)
+
+	for i := 0; i < len(audio); i += hop {
+		end := i + hop
+
+		if end > len(audio) {
+			end = len(audio)
+		}
+
+		env = append(env, rms(audio[i:end]))
+	}
+
+	return env
+}
+
+// speechLevel estimates the level separating speech from the noise floor
+func speechLevel(env []float64) float64 {
+	sorted := make([]float64, len(env))
+	copy(sorted, env)
+	sort.Float64s(sorted)
+
+	floor := sorted[len(sorted)/10]
+
+	return math.Max(floor*3, 0.003)
+}
@@ -0,0 +1,103 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"math"
+	"testing"
+)
+
+func TestKeywordLocator(t *testing.T) {
+	p := NewParams()
+
+	// Quiet noise with a 0.5s tone starting at 1s
+	audio := make([]int16, 3*p.SampleRate)
+
+	for i := range audio {
+		audio[i] = int16(i%7) - 3
+
+		if i >= 16000 && i < 24000 {
+			audio[i] = int16(8000 * math.Sin(float64(i)*0.1))
+		}
+	}
+
+	k := newKeywordLocator(p)
+
+	// Predictions rise shortly after the end of the tone, and the activation
+	// fires at the end of the audio
+	for end := int64(1600); end <= int64(len(audio)); end += 1600 {
+		var prob float32
+
+		if end >= 25600 {
+			prob = 0.9
+		}
+
+		k.add(end, prob)
+	}
+
+	segment := k.locate(audio, int64(len(audio)), 0.5)
+
+	if segment.Start != 16000 || segment.End != 24000 {
+		t.Fatal("unexpected segment", segment)
+	}
+
+	a := Activation{
+		Position: int64(len(audio)),
+		Audio:    audio[8000:],
+		Keyword:  segment,
+	}
+
+	if got := a.KeywordAudio(); len(got) != 8000 || got[0] != audio[16000] {
+		t.Fatal("unexpected keyword audio", len(got))
+	}
+}
+
+func TestKeywordLocatorRefinement(t *testing.T) {
+	p := NewParams()
+	hop := int64(p.HopSamples())
+
+	calls := 0
+
+	// The model accepts the sixth window it scores, 0.3s into the keyword
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls >= 6 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := newKeywordLocator(p, WithLocatorRefinement())
+
+	// 3s of audio ending at 148000, so it starts at 100000 in the stream
+	audio := make([]int16, 3*p.SampleRate)
+	position := int64(148000)
+
+	segment, err := k.refineEnd(l, audio, position, Segment{Start: 116000, End: 124000}, 0.5)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if segment.Start != 116000 || segment.End != 116000+6*hop || calls != 6 {
+		t.Fatal("unexpected refined segment", segment, "after", calls, "scores")
+	}
+
+	// A keyword at the end of the audio only has the windows before the edge to search
+	calls = 0
+
+	segment, err = k.refineEnd(l, audio, position, Segment{Start: position - hop - hop/2, End: position}, 0.5)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if segment.End != position || calls != 1 {
+		t.Fatal("expected the end to be kept when the search reaches the edge, got", segment, "after", calls, "scores")
+	}
+}
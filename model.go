@@ -2,14 +2,107 @@ package precise
 
 import (
 	"errors"
+	"fmt"
 	"gorgonia.org/tensor"
+	"io/fs"
+	"os"
 )
 
 var (
 	ErrUnexpectedType = errors.New("unexpected tensor type")
+	ErrInputShape     = errors.New("model input shape doesn't match params")
 )
 
 type Model interface {
 	Predict(inputData tensor.Tensor) (float32, error)
 	Close() error
 }
+
+// ModelDataLoader loads a model from its data, such as NewTFLiteModelFromBytes
+type ModelDataLoader func(data []byte) (Model, error)
+
+// LoadModelFS loads the model in the file name from fsys, such as an embed.FS, with load
+func LoadModelFS(fsys fs.FS, name string, load ModelDataLoader) (Model, error) {
+	data, err := fs.ReadFile(fsys, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := load(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return model, nil
+}
+
+// loadModelData loads a model from memory with a loader which reads files, through a temporary file with the extension
+func loadModelData(data []byte, ext string, load ModelLoader) (Model, error) {
+	// Loaders only need the file until the model is loaded
+	f, err := os.CreateTemp("", "precise-model-*"+ext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return load(f.Name())
+}
+
+// ShapedModel is a Model which reports the shape of its input, such as a TFLiteModel
+type ShapedModel interface {
+	Model
+	// InputShape returns the input dimensions, with -1 or 0 for dynamic dimensions
+	InputShape() []int
+}
+
+// CheckInputShape returns an error if the model reports an input shape which doesn't fit the features from p.
+// Models which don't report their shape are assumed to fit.
+func CheckInputShape(m Model, p Params) error {
+	shaped, ok := m.(ShapedModel)
+
+	if !ok {
+		return nil
+	}
+
+	shape := shaped.InputShape()
+
+	if shape == nil {
+		return nil
+	}
+
+	expected := []int{p.NFeatures(), p.NMFCC}
+
+	if len(shape) < len(expected) {
+		return fmt.Errorf("%w: input %v, features %v", ErrInputShape, shape, expected)
+	}
+
+	// Leading dimensions are the batch, which must be a single input
+	leading := shape[:len(shape)-len(expected)]
+
+	for _, dim := range leading {
+		if dim > 1 {
+			return fmt.Errorf("%w: input %v, features %v", ErrInputShape, shape, expected)
+		}
+	}
+
+	for i, dim := range shape[len(leading):] {
+		if dim > 0 && dim != expected[i] {
+			return fmt.Errorf("%w: input %v, features %v", ErrInputShape, shape, expected)
+		}
+	}
+
+	return nil
+}
@@ -1,12 +1,12 @@
-//go:build onnx
+//go:build onnx && cgo
 
 package precise
 
 import (
-	"context"
 	"errors"
 	"github.com/ivansuteja96/go-onnxruntime"
 	"gorgonia.org/tensor"
+	"io"
 )
 
 type DeviceType int
@@ -29,8 +29,59 @@ func (t DeviceType) String() string {
 	return ""
 }
 
+func init() {
+	RegisterBackend(onnxBackend{})
+}
+
+// onnxBackend loads onnx models on the CPU, and is included in builds with cgo and the onnx tag
+type onnxBackend struct{}
+
+func (onnxBackend) Name() string {
+	return "onnx"
+}
+
+func (onnxBackend) Load(data []byte) (Model, error) {
+	return NewONNXModelFromBytes(data, OnnxCPU)
+}
+
+func (onnxBackend) LoadFile(path string) (Model, error) {
+	return NewONNXModel(path, OnnxCPU)
+}
+
 // NewONNXModel creates a new onnx model
 func NewONNXModel(modelPath string, deviceType DeviceType) (Model, error) {
+	model, err := newONNXSession(modelPath, deviceType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ONNXModel{
+		model: model,
+	}, nil
+}
+
+// NewONNXModelFromBytes creates a new onnx model from the model's data, such as from go:embed.
+// onnxruntime is only given a path, so the data is written to a temporary file until the session is created.
+func NewONNXModelFromBytes(data []byte, deviceType DeviceType) (Model, error) {
+	return loadModelData(data, ".onnx", func(path string) (Model, error) {
+		return NewONNXModel(path, deviceType)
+	})
+}
+
+// NewONNXModelFromReader creates a new onnx model, reading the model's data from r
+func NewONNXModelFromReader(r io.Reader, deviceType DeviceType) (Model, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewONNXModelFromBytes(data, deviceType)
+}
+
+// newONNXSession creates an onnxruntime session for the given device type
+func newONNXSession(modelPath string, deviceType DeviceType) (*onnxruntime.ORTSession, error) {
 	ortEnvDet := onnxruntime.NewORTEnv(onnxruntime.ORT_LOGGING_LEVEL_ERROR, "development")
 	ortDetSO := onnxruntime.NewORTSessionOptions()
 
@@ -51,21 +102,12 @@ func NewONNXModel(modelPath string, deviceType DeviceType) (Model, error) {
 		})
 	}
 
-	model, err := onnxruntime.NewORTSession(ortEnvDet, modelPath, ortDetSO)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &ONNXModel{
-		model: model,
-	}, nil
+	return onnxruntime.NewORTSession(ortEnvDet, modelPath, ortDetSO)
 }
 
 // ONNXModel represents a tensorflow lite model
 type ONNXModel struct {
 	model *onnxruntime.ORTSession
-	ctx   context.Context
 }
 
 // Predict sends the input data into the input tensor, then invokes the model
@@ -100,8 +142,15 @@ func (m *ONNXModel) Predict(inputData tensor.Tensor) (float32, error) {
 	return -1, errors.New("unexpected output value type")
 }
 
-// Close cleans up the model after use
+// Close cleans up the model after use, freeing its onnxruntime session
 func (m *ONNXModel) Close() error {
+	if m.model == nil {
+		return nil
+	}
+
+	err := m.model.Close()
+
 	m.model = nil
-	return nil
+
+	return err
 }
@@ -0,0 +1,33 @@
+package precise
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadModelFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"models/hey-astra.tflite": {Data: []byte{255}},
+		"models/empty.tflite":     {Data: []byte{}},
+	}
+
+	model, err := LoadModelFS(fsys, "models/hey-astra.tflite", fakeModel)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score, _ := model.Predict(nil); score != 1 {
+		t.Errorf("expected the model's data to be loaded, got a score of %.2f", score)
+	}
+
+	if _, err := LoadModelFS(fsys, "models/missing.tflite", fakeModel); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	if _, err := LoadModelFS(fsys, "models/empty.tflite", fakeModel); err == nil {
+		t.Error("expected the loader's error")
+	}
+}
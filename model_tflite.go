@@ -1,16 +1,57 @@
+//go:build cgo && !notflite
+
 package precise
 
 import (
 	"errors"
 	"github.com/mattn/go-tflite"
 	"gorgonia.org/tensor"
+	"io"
 	"sync"
 )
 
+func init() {
+	RegisterBackend(tfliteBackend{})
+}
+
+// tfliteBackend loads tensorflow lite models, and is included in builds with cgo unless the notflite tag is set
+type tfliteBackend struct{}
+
+func (tfliteBackend) Name() string {
+	return "tflite"
+}
+
+func (tfliteBackend) Load(data []byte) (Model, error) {
+	return NewTFLiteModelFromBytes(data)
+}
+
+func (tfliteBackend) LoadFile(path string) (Model, error) {
+	return NewTFLiteModel(path)
+}
+
 // NewTFLiteModel creates a new tensorflow lite model
 func NewTFLiteModel(modelPath string) (Model, error) {
-	model := tflite.NewModelFromFile(modelPath)
+	return newTFLiteModel(tflite.NewModelFromFile(modelPath))
+}
+
+// NewTFLiteModelFromBytes creates a new tensorflow lite model from the model's data, such as from go:embed.
+// go-tflite copies data it's given into memory it never frees, so the data is loaded through a temporary file.
+func NewTFLiteModelFromBytes(data []byte) (Model, error) {
+	return loadModelData(data, ".tflite", NewTFLiteModel)
+}
+
+// NewTFLiteModelFromReader creates a new tensorflow lite model, reading the model's data from r
+func NewTFLiteModelFromReader(r io.Reader) (Model, error) {
+	data, err := io.ReadAll(r)
 
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTFLiteModelFromBytes(data)
+}
+
+func newTFLiteModel(model *tflite.Model) (Model, error) {
 	if model == nil {
 		return nil, errors.New("cannot load model")
 	}
@@ -58,6 +99,18 @@ func (m *TFLiteModel) Predict(inputData tensor.Tensor) (float32, error) {
 	return output.Float32s()[0], nil
 }
 
+// InputShape returns the shape of the model's input tensor
+func (m *TFLiteModel) InputShape() []int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.model == nil {
+		return nil
+	}
+
+	return m.interpreter.GetInputTensor(0).Shape()
+}
+
 // Close cleans up the model after use
 func (m *TFLiteModel) Close() error {
 	m.lock.Lock()
@@ -0,0 +1,234 @@
+package precise
+
+import (
+	"github.com/yut-kt/goft"
+	"math"
+	"math/cmplx"
+	"sync"
+	"time"
+)
+
+const (
+	// noiseFrameSize and noiseHopSize are the STFT frame and hop, in samples
+	noiseFrameSize = 512
+	noiseHopSize   = 256
+	// noiseBias compensates for the minimum of the smoothed power being below the mean noise power
+	noiseBias = 2
+)
+
+type NoiseOption func(*NoiseSuppressor)
+
+// WithNoiseStrength sets how much of the noise estimate is removed, where 1 removes the
+// estimate, higher values remove more at the cost of distortion, and 0 disables suppression
+func WithNoiseStrength(strength float64) NoiseOption {
+	return func(n *NoiseSuppressor) {
+		n.strength = strength
+	}
+}
+
+// WithNoiseFloorGain sets the smallest gain applied to any frequency, limiting artifacts
+func WithNoiseFloorGain(gain float64) NoiseOption {
+	return func(n *NoiseSuppressor) {
+		n.floorGain = gain
+	}
+}
+
+// WithNoiseAdaptation sets how long the noise estimate takes to rise to a louder noise floor
+func WithNoiseAdaptation(d time.Duration) NoiseOption {
+	return func(n *NoiseSuppressor) {
+		n.adaptation = d
+	}
+}
+
+// WithNoiseBypassLevel sets the noise RMS level, from 0 to 1, below which suppression is bypassed
+func WithNoiseBypassLevel(level float64) NoiseOption {
+	return func(n *NoiseSuppressor) {
+		n.bypassLevel = level
+	}
+}
+
+// NewNoiseSuppressor creates a streaming noise suppressor, using spectral subtraction
+// with a tracked noise floor. Output is delayed by 512 samples.
+// A NoiseSuppressor keeps state for a single stream, so each Listener needs its own.
+func NewNoiseSuppressor(sampleRate int, opts ...NoiseOption) *NoiseSuppressor {
+	n := &NoiseSuppressor{
+		sampleRate:  sampleRate,
+		strength:    1,
+		floorGain:   0.1,
+		adaptation:  2 * time.Second,
+		bypassLevel: 0.0005,
+		window:      make([]float64, noiseFrameSize),
+		lock:        new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	// A square root Hann window, applied before and after filtering, sums to 1 at 50% overlap
+	for i := range n.window {
+		n.window[i] = math.Sqrt(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/noiseFrameSize))
+	}
+
+	// Rise by a factor of 10 over the adaptation time
+	hopT := float64(noiseHopSize) / float64(sampleRate)
+	n.rise = math.Pow(10, hopT/n.adaptation.Seconds())
+
+	n.reset()
+
+	return n
+}
+
+// NoiseSuppressor is a Processor which reduces stationary background noise, such as fans or music
+type NoiseSuppressor struct {
+	sampleRate  int
+	strength    float64
+	floorGain   float64
+	adaptation  time.Duration
+	bypassLevel float64
+	rise        float64
+	window      []float64
+	input       []float64
+	overlap     []float64
+	output      []float64
+	power       []float64
+	noise       []float64
+	started     bool
+	bypass      bool
+	autoBypass  bool
+	lock        *sync.Mutex
+}
+
+// Process suppresses noise in samples, returning a new slice of the same length
+func (n *NoiseSuppressor) Process(samples []int16) []int16 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for _, s := range samples {
+		n.input = append(n.input, float64(s))
+	}
+
+	for len(n.input) >= noiseFrameSize {
+		n.processFrame(n.input[:noiseFrameSize])
+		n.input = n.input[noiseHopSize:]
+	}
+
+	out := make([]int16, len(samples))
+
+	for i := range out {
+		out[i] = int16(math.Max(math.Min(math.Round(n.output[i]), math.MaxInt16), math.MinInt16))
+	}
+
+	n.output = n.output[len(out):]
+
+	return out
+}
+
+// processFrame filters a single frame, adding a hop of output
+func (n *NoiseSuppressor) processFrame(frame []float64) {
+	windowed := make([]float64, noiseFrameSize)
+
+	for i, v := range frame {
+		windowed[i] = v * n.window[i]
+	}
+
+	// goft only returns an error for lengths which aren't a power of two
+	spectrum, _ := goft.FFT(windowed)
+
+	bins := noiseFrameSize/2 + 1
+
+	var noisePower float64
+
+	for k := 0; k < bins; k++ {
+		p := real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+
+		// Smooth the power, then track its minimum as the noise floor
+		if !n.started {
+			n.power[k] = p
+			n.noise[k] = p
+		} else {
+			n.power[k] = 0.85*n.power[k] + 0.15*p
+		}
+
+		if n.power[k] < n.noise[k] {
+			n.noise[k] = n.power[k]
+		} else {
+			n.noise[k] *= n.rise
+		}
+
+		noisePower += n.noise[k]
+	}
+
+	n.started = true
+
+	// Convert the noise power to an RMS level from 0 to 1, allowing for the window
+	level := math.Sqrt(2*noisePower/float64(noiseFrameSize*noiseFrameSize/2)) / 32768
+	n.autoBypass = level < n.bypassLevel
+
+	if !n.bypass && !n.autoBypass && n.strength > 0 {
+		for k := 0; k < bins; k++ {
+			gain := n.floorGain
+
+			if n.power[k] > 0 {
+				gain = math.Max(n.floorGain, 1-n.strength*noiseBias*n.noise[k]/n.power[k])
+			}
+
+			spectrum[k] *= complex(gain, 0)
+
+			// Keep the spectrum conjugate symmetric, so the output is real
+			if k > 0 && k < noiseFrameSize/2 {
+				spectrum[noiseFrameSize-k] = cmplx.Conj(spectrum[k])
+			}
+		}
+	}
+
+	filtered, _ := goft.IFFT(spectrum)
+
+	for i := range n.overlap {
+		n.overlap[i] += real(filtered[i]) * n.window[i]
+	}
+
+	n.output = append(n.output, n.overlap[:noiseHopSize]...)
+
+	copy(n.overlap, n.overlap[noiseHopSize:])
+
+	for i := noiseFrameSize - noiseHopSize; i < noiseFrameSize; i++ {
+		n.overlap[i] = 0
+	}
+}
+
+// SetBypass enables or disables suppression, while continuing to track the noise floor
+func (n *NoiseSuppressor) SetBypass(bypass bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.bypass = bypass
+}
+
+// Bypassed returns true if suppression is bypassed, either by SetBypass or
+// because the noise floor is below the bypass level
+func (n *NoiseSuppressor) Bypassed() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.bypass || n.autoBypass
+}
+
+// Reset clears the noise estimate and any buffered audio
+func (n *NoiseSuppressor) Reset() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.reset()
+}
+
+func (n *NoiseSuppressor) reset() {
+	// Priming the buffers gives a constant delay, so each call returns as many samples as it is given
+	n.input = make([]float64, noiseFrameSize-noiseHopSize)
+	n.output = make([]float64, noiseHopSize)
+	n.overlap = make([]float64, noiseFrameSize)
+	n.power = make([]float64, noiseFrameSize/2+1)
+	n.noise = make([]float64, noiseFrameSize/2+1)
+	n.started = false
+	n.autoBypass = false
+}
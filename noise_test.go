@@ -0,0 +1,111 @@
+package precise
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// toneBursts creates a 1kHz tone which is on for 200ms out of every 500ms, like speech
+func toneBursts(amplitude float64, n int) []int16 {
+	samples := tone(1000, amplitude, n)
+
+	for i := range samples {
+		if i%8000 >= 3200 {
+			samples[i] = 0
+		}
+	}
+
+	return samples
+}
+
+// addNoise adds white noise with the given RMS level to samples
+func addNoise(samples []int16, level float64) []int16 {
+	r := rand.New(rand.NewSource(1))
+
+	out := make([]int16, len(samples))
+
+	for i, s := range samples {
+		out[i] = int16(math.Max(math.Min(float64(s)+r.NormFloat64()*level*32768, math.MaxInt16), math.MinInt16))
+	}
+
+	return out
+}
+
+// processChunks runs samples through a processor in runner sized chunks
+func processChunks(p Processor, samples []int16) []int16 {
+	var out []int16
+
+	for i := 0; i < len(samples); i += 1000 {
+		end := i + 1000
+
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		out = append(out, p.Process(samples[i:end])...)
+	}
+
+	return out
+}
+
+func TestNoiseSuppressor(t *testing.T) {
+	clean := toneBursts(0.3, 64000)
+	noisy := addNoise(clean, 0.03)
+
+	n := NewNoiseSuppressor(16000)
+	out := processChunks(n, noisy)
+
+	if len(out) != len(noisy) {
+		t.Fatal("expected", len(noisy), "samples, got", len(out))
+	}
+
+	// Compare the noise between bursts in the last second, allowing for the delay
+	var noiseBefore, noiseAfter float64
+
+	for i := 48000; i < 64000-noiseFrameSize; i++ {
+		if i%8000 >= 3300 && i%8000 < 7900 {
+			noiseBefore += float64(noisy[i]) * float64(noisy[i])
+			noiseAfter += float64(out[i+noiseFrameSize]) * float64(out[i+noiseFrameSize])
+		}
+	}
+
+	if reduction := 10 * math.Log10(noiseBefore/noiseAfter); reduction < 10 {
+		t.Fatalf("expected noise to be reduced by at least 10dB, got %.1fdB", reduction)
+	}
+
+	// The tone is mostly preserved
+	toneLevel := rms(out[48000+noiseFrameSize+400 : 48000+noiseFrameSize+2800])
+
+	if toneLevel < 0.15 {
+		t.Fatalf("expected the tone to be preserved, got level %.3f", toneLevel)
+	}
+
+	if n.Bypassed() {
+		t.Fatal("expected suppression to be active")
+	}
+}
+
+func TestNoiseSuppressorBypass(t *testing.T) {
+	input := addNoise(toneBursts(0.3, 16000), 0.03)
+
+	n := NewNoiseSuppressor(16000)
+	n.SetBypass(true)
+
+	out := processChunks(n, input)
+
+	// Bypassed audio is only delayed
+	for i := 0; i < len(input)-noiseFrameSize; i++ {
+		if d := int(out[i+noiseFrameSize]) - int(input[i]); d > 1 || d < -1 {
+			t.Fatalf("expected bypassed audio to be unchanged, sample %d differs by %d", i, d)
+		}
+	}
+
+	// A quiet noise floor bypasses itself
+	n = NewNoiseSuppressor(16000)
+	processChunks(n, addNoise(make([]int16, 16000), 0.0001))
+
+	if !n.Bypassed() {
+		t.Fatal("expected suppression to bypass itself with a quiet noise floor")
+	}
+}
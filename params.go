@@ -18,6 +18,11 @@ type Params struct {
 	UseDelta        bool    `json:"use_delta" default:"false"`
 	ThresholdConfig MuStd   `json:"threshold_config" default:"[[6,4]]"`
 	ThresholdCenter float32 `json:"threshold_center" default:"0.2"`
+	// DCBlock, HighPass and PreEmphasis select preprocessing filters, which should match
+	// those used in training. HighPass is a cutoff in Hz, and both are disabled when 0.
+	DCBlock     bool    `json:"dc_block" default:"false"`
+	HighPass    float32 `json:"high_pass" default:"0"`
+	PreEmphasis float32 `json:"pre_emphasis" default:"0"`
 }
 
 func NewParams() Params {
@@ -0,0 +1,165 @@
+package precise
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// defaultChunkSize is the chunk size, in bytes, used when none is given
+const defaultChunkSize = 2048
+
+// FileResult is the output of processing a file
+type FileResult struct {
+	// ChunkSamples is the number of samples passed to the model per prediction
+	ChunkSamples int
+	// Predictions holds the probability for each chunk
+	Predictions []float32
+	Activations []Activation
+}
+
+// ProcessFile decodes an audio file of any registered format, converts it to
+// match the listener's Params and runs it through the model, returning
+// predictions and detections
+func (p *Listener) ProcessFile(path string, opts ...TriggerOption) (*FileResult, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s, err := DecodeAudio(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return p.ProcessStream(s, opts...)
+}
+
+// ProcessStream converts an AudioStream to match the listener's Params, and
+// runs it through the model from a clean state
+func (p *Listener) ProcessStream(s AudioStream, opts ...TriggerOption) (*FileResult, error) {
+	converter, err := NewStreamConverter(s, p.params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fp := p.newFileProcessor(opts...)
+
+	for {
+		samples, err := converter.Next()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if err := fp.add(samples); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fp.flush(); err != nil {
+		return nil, err
+	}
+
+	return fp.res, nil
+}
+
+// ProcessSamples runs samples through the model from a clean state, returning
+// predictions and detections
+func (p *Listener) ProcessSamples(samples []int16, opts ...TriggerOption) (*FileResult, error) {
+	fp := p.newFileProcessor(opts...)
+
+	if err := fp.add(samples); err != nil {
+		return nil, err
+	}
+
+	if err := fp.flush(); err != nil {
+		return nil, err
+	}
+
+	return fp.res, nil
+}
+
+// newFileProcessor resets the listener and creates a fileProcessor
+func (p *Listener) newFileProcessor(opts ...TriggerOption) *fileProcessor {
+	p.Reset()
+
+	chunkSamples := defaultChunkSize / p.params.SampleDepth
+
+	return &fileProcessor{
+		listener:     p,
+		detector:     NewTriggerDetector(defaultChunkSize, opts...),
+		chunkSamples: chunkSamples,
+		pending:      make([]int16, 0, chunkSamples),
+		res: &FileResult{
+			ChunkSamples: chunkSamples,
+		},
+	}
+}
+
+// fileProcessor splits audio into fixed size chunks for the listener
+type fileProcessor struct {
+	listener     *Listener
+	detector     *TriggerDetector
+	chunkSamples int
+	pending      []int16
+	position     int64
+	res          *FileResult
+}
+
+// add buffers samples, processing each complete chunk
+func (fp *fileProcessor) add(samples []int16) error {
+	for len(samples) > 0 {
+		n := fp.chunkSamples - len(fp.pending)
+
+		if n > len(samples) {
+			n = len(samples)
+		}
+
+		fp.pending = append(fp.pending, samples[:n]...)
+		samples = samples[n:]
+
+		if len(fp.pending) == fp.chunkSamples {
+			if err := fp.flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flush processes any pending samples as a chunk
+func (fp *fileProcessor) flush() error {
+	if len(fp.pending) == 0 {
+		return nil
+	}
+
+	prob, err := fp.listener.Update(fp.pending)
+
+	if err != nil {
+		return err
+	}
+
+	fp.position += int64(len(fp.pending))
+	fp.pending = fp.pending[:0]
+
+	fp.res.Predictions = append(fp.res.Predictions, prob)
+
+	if fp.detector.Update(prob) {
+		fp.res.Activations = append(fp.res.Activations, Activation{
+			Probability: prob,
+			SampleRate:  fp.listener.params.SampleRate,
+			Position:    fp.position,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package precise
+
+// Processor is a stage applied to audio before feature extraction.
+// Processors keep state for a single stream, so each Listener needs its own.
+type Processor interface {
+	// Process returns the processed samples, without modifying samples
+	Process(samples []int16) []int16
+	// Reset clears any state carried between chunks
+	Reset()
+}
+
+type ListenerOption func(*Listener)
+
+// WithProcessor adds a Processor, such as an AGC, applied to audio before feature extraction.
+// Processors are applied in the order they are added.
+func WithProcessor(processor Processor) ListenerOption {
+	return func(l *Listener) {
+		l.processors = append(l.processors, processor)
+	}
+}
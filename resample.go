@@ -0,0 +1,119 @@
+package precise
+
+import "math"
+
+// resamplerZeroCrossings is the number of sinc zero crossings on each side of the filter
+const resamplerZeroCrossings = 16
+
+// NewResampler creates a streaming resampler between two sample rates
+func NewResampler(inRate, outRate int) *Resampler {
+	r := &Resampler{
+		ratio:  float64(inRate) / float64(outRate),
+		cutoff: 1.0,
+	}
+
+	// Lower the cutoff below the output Nyquist frequency when downsampling
+	if outRate < inRate {
+		r.cutoff = float64(outRate) / float64(inRate)
+	}
+
+	r.halfWidth = int(math.Ceil(resamplerZeroCrossings / r.cutoff))
+
+	r.Reset()
+
+	return r
+}
+
+// Resampler converts audio between sample rates using a windowed sinc filter.
+// State is kept between calls to Process, so chunks of a stream can be passed in
+// as they arrive.
+type Resampler struct {
+	ratio     float64
+	cutoff    float64
+	halfWidth int
+	buf       []float64
+	pos       float64
+}
+
+// Process resamples a chunk of samples, returning as many output samples as
+// are available. Output lags the input by the filter's half width.
+func (r *Resampler) Process(in []float32) []float32 {
+	if r.ratio == 1 {
+		return in
+	}
+
+	for _, s := range in {
+		r.buf = append(r.buf, float64(s))
+	}
+
+	out := make([]float32, 0, int(float64(len(in))/r.ratio)+1)
+
+	for int(r.pos)+r.halfWidth < len(r.buf) {
+		out = append(out, float32(r.sample(r.pos)))
+		r.pos += r.ratio
+	}
+
+	// Drop input that is no longer needed by the filter
+	if drop := int(r.pos) - r.halfWidth; drop > 0 {
+		r.buf = r.buf[:copy(r.buf, r.buf[drop:])]
+		r.pos -= float64(drop)
+	}
+
+	return out
+}
+
+// Flush returns the remaining output, padding the input with silence
+func (r *Resampler) Flush() []float32 {
+	if r.ratio == 1 {
+		return nil
+	}
+
+	out := r.Process(make([]float32, r.halfWidth))
+
+	r.Reset()
+
+	return out
+}
+
+// Reset clears the resampler state
+func (r *Resampler) Reset() {
+	// Start with a half width of silence, so the first output is centered on the first input
+	r.buf = make([]float64, r.halfWidth)
+	r.pos = float64(r.halfWidth)
+}
+
+// sample interpolates the input at position t
+func (r *Resampler) sample(t float64) float64 {
+	center := int(t)
+
+	var sum float64
+
+	for k := center - r.halfWidth + 1; k <= center+r.halfWidth; k++ {
+		if k < 0 || k >= len(r.buf) {
+			continue
+		}
+
+		x := t - float64(k)
+
+		sum += r.buf[k] * r.kernel(x)
+	}
+
+	return sum
+}
+
+// kernel is a Hann windowed sinc, scaled for the cutoff frequency
+func (r *Resampler) kernel(x float64) float64 {
+	if math.Abs(x) >= float64(r.halfWidth) {
+		return 0
+	}
+
+	window := 0.5 * (1 + math.Cos(math.Pi*x/float64(r.halfWidth)))
+
+	arg := math.Pi * x * r.cutoff
+
+	if arg == 0 {
+		return r.cutoff * window
+	}
+
+	return r.cutoff * math.Sin(arg) / arg * window
+}
@@ -0,0 +1,116 @@
+package precise
+
+import "sync"
+
+// newSampleRing creates a ring buffer holding the last size samples
+func newSampleRing(size int) *sampleRing {
+	return &sampleRing{
+		buf:  make([]int16, size),
+		lock: new(sync.Mutex),
+	}
+}
+
+// sampleRing is a fixed size ring buffer of recent samples.
+// Writes copy into the existing buffer, so there are no allocations while streaming.
+type sampleRing struct {
+	lock  *sync.Mutex
+	buf   []int16
+	pos   int
+	total int64
+	// held is the number of samples in the buffer written since it was reset
+	held int
+}
+
+// Write adds samples to the buffer, overwriting the oldest samples
+func (r *sampleRing) Write(samples []int16) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.total += int64(len(samples))
+	r.held += len(samples)
+
+	if r.held > len(r.buf) {
+		r.held = len(r.buf)
+	}
+
+	if len(samples) >= len(r.buf) {
+		copy(r.buf, samples[len(samples)-len(r.buf):])
+		r.pos = 0
+		return
+	}
+
+	n := copy(r.buf[r.pos:], samples)
+
+	if n < len(samples) {
+		copy(r.buf, samples[n:])
+	}
+
+	r.pos = (r.pos + len(samples)) % len(r.buf)
+}
+
+// Last returns a copy of the last n samples, or fewer if the buffer holds less
+func (r *sampleRing) Last(n int) []int16 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.last(n)
+}
+
+// Ending returns a copy of up to n samples ending at a total, or fewer if the buffer no longer holds them
+func (r *sampleRing) Ending(end int64, n int) []int16 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	skip := int(r.total - end)
+
+	if skip < 0 {
+		skip = 0
+	}
+
+	out := r.last(n + skip)
+
+	if skip >= len(out) {
+		return nil
+	}
+
+	return out[:len(out)-skip]
+}
+
+func (r *sampleRing) last(n int) []int16 {
+	if n > len(r.buf) {
+		n = len(r.buf)
+	}
+
+	if n > r.held {
+		n = r.held
+	}
+
+	out := make([]int16, n)
+
+	start := r.pos - n
+
+	if start < 0 {
+		copied := copy(out, r.buf[len(r.buf)+start:])
+		copy(out[copied:], r.buf[:r.pos])
+	} else {
+		copy(out, r.buf[start:r.pos])
+	}
+
+	return out
+}
+
+// Reset empties the buffer, keeping the total written
+func (r *sampleRing) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.held = 0
+}
+
+// Total returns the number of samples written since the buffer was created
+func (r *sampleRing) Total() int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.total
+}
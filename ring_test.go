@@ -0,0 +1,107 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"testing"
+	"time"
+)
+
+func sequence(start, n int) []int16 {
+	out := make([]int16, n)
+
+	for i := range out {
+		out[i] = int16(start + i)
+	}
+
+	return out
+}
+
+func equalSamples(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSampleRing(t *testing.T) {
+	r := newSampleRing(10)
+
+	r.Write(sequence(0, 4))
+
+	if got := r.Last(10); !equalSamples(got, sequence(0, 4)) {
+		t.Fatal("unexpected samples before wrap", got)
+	}
+
+	r.Write(sequence(4, 8))
+
+	if got := r.Last(10); !equalSamples(got, sequence(2, 10)) {
+		t.Fatal("unexpected samples after wrap", got)
+	}
+
+	if got := r.Last(3); !equalSamples(got, sequence(9, 3)) {
+		t.Fatal("unexpected tail", got)
+	}
+
+	r.Write(sequence(100, 25))
+
+	if got := r.Last(10); !equalSamples(got, sequence(115, 10)) {
+		t.Fatal("unexpected samples after oversized write", got)
+	}
+}
+
+func TestRunnerPreRoll(t *testing.T) {
+	p := NewParams()
+
+	calls := 0
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls >= 3 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan Activation, 1)
+
+	r := NewRunner(l, 1600,
+		WithPreRoll(500*time.Millisecond),
+		WithDetectorOpts(WithTriggerLevel(1)),
+		WithActivationEventFunc(func(a Activation) {
+			ch <- a
+		}),
+	)
+
+	defer r.Close()
+
+	for i := 0; i < 10; i++ {
+		r.Queue(sequence(i*1600, 1600))
+	}
+
+	select {
+	case a := <-ch:
+		// Activation fires on the fourth chunk, before the 500ms buffer has filled
+		if !equalSamples(a.Audio, sequence(0, 4*1600)) {
+			t.Fatal("unexpected pre-roll length", len(a.Audio))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no activation")
+	}
+
+	if got := r.RecentAudio(100 * time.Millisecond); len(got) != 1600 {
+		t.Fatal("expected 1600 recent samples, got", len(got))
+	}
+}
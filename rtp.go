@@ -0,0 +1,508 @@
+package precise
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRTP = errors.New("invalid rtp packet")
+)
+
+// RTPPacket is a parsed RTP packet
+type RTPPacket struct {
+	Marker      bool
+	PayloadType uint8
+	Sequence    uint16
+	Timestamp   uint32
+	SSRC        uint32
+	Payload     []byte
+}
+
+// ParseRTP parses an RTP packet. The payload references b.
+func ParseRTP(b []byte) (*RTPPacket, error) {
+	if len(b) < 12 {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than the header", ErrInvalidRTP, len(b))
+	}
+
+	if version := b[0] >> 6; version != 2 {
+		return nil, fmt.Errorf("%w: version %d", ErrInvalidRTP, version)
+	}
+
+	p := &RTPPacket{
+		Marker:      b[1]&0x80 != 0,
+		PayloadType: b[1] & 0x7F,
+		Sequence:    binary.BigEndian.Uint16(b[2:4]),
+		Timestamp:   binary.BigEndian.Uint32(b[4:8]),
+		SSRC:        binary.BigEndian.Uint32(b[8:12]),
+	}
+
+	offset := 12 + 4*int(b[0]&0x0F)
+
+	if b[0]&0x10 != 0 {
+		if len(b) < offset+4 {
+			return nil, fmt.Errorf("%w: truncated extension", ErrInvalidRTP)
+		}
+
+		offset += 4 + 4*int(binary.BigEndian.Uint16(b[offset+2:offset+4]))
+	}
+
+	end := len(b)
+
+	if b[0]&0x20 != 0 && end > 0 {
+		end -= int(b[end-1])
+	}
+
+	if offset > end {
+		return nil, fmt.Errorf("%w: header longer than packet", ErrInvalidRTP)
+	}
+
+	p.Payload = b[offset:end]
+
+	return p, nil
+}
+
+// Encoding is an RTP audio payload encoding
+type Encoding int
+
+const (
+	EncodingPCMU Encoding = iota
+	EncodingPCMA
+	EncodingL16
+)
+
+// PayloadFormat describes the audio carried by an RTP payload type
+type PayloadFormat struct {
+	Encoding   Encoding
+	SampleRate int
+	Channels   int
+}
+
+// decode converts a payload to mono 16-bit samples
+func (f PayloadFormat) decode(payload []byte) []int16 {
+	var samples []int16
+
+	switch f.Encoding {
+	case EncodingPCMU:
+		samples = make([]int16, len(payload))
+
+		for i, b := range payload {
+			samples[i] = ulawToLinear(b)
+		}
+	case EncodingPCMA:
+		samples = make([]int16, len(payload))
+
+		for i, b := range payload {
+			samples[i] = alawToLinear(b)
+		}
+	case EncodingL16:
+		samples = make([]int16, len(payload)/2)
+
+		for i := range samples {
+			samples[i] = int16(binary.BigEndian.Uint16(payload[i*2:]))
+		}
+	}
+
+	if f.Channels <= 1 {
+		return samples
+	}
+
+	mono := make([]int16, len(samples)/f.Channels)
+
+	for i := range mono {
+		var sum int
+
+		for c := 0; c < f.Channels; c++ {
+			sum += int(samples[i*f.Channels+c])
+		}
+
+		mono[i] = int16(sum / f.Channels)
+	}
+
+	return mono
+}
+
+// staticPayloadFormats are the audio payload types assigned in RFC 3551
+var staticPayloadFormats = map[uint8]PayloadFormat{
+	0:  {Encoding: EncodingPCMU, SampleRate: 8000, Channels: 1},
+	8:  {Encoding: EncodingPCMA, SampleRate: 8000, Channels: 1},
+	10: {Encoding: EncodingL16, SampleRate: 44100, Channels: 2},
+	11: {Encoding: EncodingL16, SampleRate: 44100, Channels: 1},
+}
+
+// RunnerFactory creates a Runner for a new stream, identified by id
+type RunnerFactory func(id uint32) (*Runner, error)
+
+// StreamErrorFunc is called when a stream can't be created, identified by id
+type StreamErrorFunc func(id uint32, err error)
+
+type RTPOption func(*RTPSource)
+
+// WithPayloadFormat sets the format of a payload type, such as a dynamic type negotiated in SDP
+func WithPayloadFormat(payloadType uint8, format PayloadFormat) RTPOption {
+	return func(s *RTPSource) {
+		s.formats[payloadType] = format
+	}
+}
+
+// WithJitterDepth sets how many packets may be waiting for a missing packet
+// before it is considered lost
+func WithJitterDepth(depth int) RTPOption {
+	return func(s *RTPSource) {
+		s.jitterDepth = depth
+	}
+}
+
+// WithStreamTimeout sets how long a stream may go without packets before its runner is closed
+func WithStreamTimeout(d time.Duration) RTPOption {
+	return func(s *RTPSource) {
+		s.streamTimeout = d
+	}
+}
+
+// WithMaxGap sets the longest gap between packets that is filled with silence, when the runner's
+// gap policy fills gaps. Longer gaps reset the runner's feature window.
+func WithMaxGap(d time.Duration) RTPOption {
+	return func(s *RTPSource) {
+		s.maxGap = d
+	}
+}
+
+// WithStreamErrorFunc sets the func called when a stream's runner can't be created.
+// The stream's packets are dropped, and the runner is created again on its next packet.
+func WithStreamErrorFunc(f StreamErrorFunc) RTPOption {
+	return func(s *RTPSource) {
+		s.OnStreamError = f
+	}
+}
+
+// ListenRTP listens for RTP on a UDP address
+func ListenRTP(addr string, params Params, factory RunnerFactory, opts ...RTPOption) (*RTPSource, error) {
+	conn, err := net.ListenPacket("udp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRTPSource(conn, params, factory, opts...), nil
+}
+
+// NewRTPSource creates an RTPSource reading from conn.
+// Each SSRC becomes a separate stream, with its own Runner created by factory.
+func NewRTPSource(conn net.PacketConn, params Params, factory RunnerFactory, opts ...RTPOption) *RTPSource {
+	s := &RTPSource{
+		conn:          conn,
+		params:        params,
+		factory:       factory,
+		formats:       make(map[uint8]PayloadFormat),
+		jitterDepth:   5,
+		streamTimeout: 30 * time.Second,
+		maxGap:        time.Second,
+		streams:       make(map[uint32]*rtpStream),
+		lock:          new(sync.Mutex),
+	}
+
+	for pt, format := range staticPayloadFormats {
+		s.formats[pt] = format
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// RTPSource receives RTP audio over UDP, feeding each stream to a Runner
+type RTPSource struct {
+	conn          net.PacketConn
+	params        Params
+	factory       RunnerFactory
+	formats       map[uint8]PayloadFormat
+	jitterDepth   int
+	streamTimeout time.Duration
+	maxGap        time.Duration
+	streams       map[uint32]*rtpStream
+	closed        bool
+	lock          *sync.Mutex
+
+	OnStreamError StreamErrorFunc
+}
+
+// Addr returns the local address packets are received on
+func (s *RTPSource) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Serve reads packets until the source is closed
+func (s *RTPSource) Serve() error {
+	buf := make([]byte, 1500)
+
+	lastExpiry := time.Now()
+
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := s.conn.ReadFrom(buf)
+
+		if err != nil {
+			if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+				if errors.Is(err, net.ErrClosed) {
+					return nil
+				}
+
+				return err
+			}
+		}
+
+		if n > 0 {
+			// Copy the packet, as it may be held in the jitter buffer
+			packet := make([]byte, n)
+			copy(packet, buf[:n])
+
+			// Invalid packets are ignored, as anything can arrive on a UDP port.
+			// A stream which can't be created doesn't stop the others.
+			if p, err := ParseRTP(packet); err == nil {
+				if err := s.handle(p); err != nil && s.OnStreamError != nil {
+					s.OnStreamError(p.SSRC, err)
+				}
+			}
+		}
+
+		if time.Since(lastExpiry) > time.Second {
+			s.expire()
+			lastExpiry = time.Now()
+		}
+	}
+}
+
+// Close stops receiving and closes every stream's runner, once its waiting packets are queued
+func (s *RTPSource) Close() error {
+	err := s.conn.Close()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+
+	for ssrc, stream := range s.streams {
+		stream.close()
+		delete(s.streams, ssrc)
+	}
+
+	return err
+}
+
+// handle passes a packet to its stream, creating the stream if needed.
+// Packets of other payload types, such as telephone events, are passed through the
+// stream's jitter buffer without audio, so they don't leave holes in the sequence.
+func (s *RTPSource) handle(p *RTPPacket) error {
+	_, audio := s.formats[p.PayloadType]
+
+	s.lock.Lock()
+
+	// Packets still being handled once the source is closed are dropped
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+
+	stream, ok := s.streams[p.SSRC]
+
+	if !ok {
+		// Streams are only created for audio
+		if !audio {
+			s.lock.Unlock()
+			return nil
+		}
+
+		runner, err := s.factory(p.SSRC)
+
+		if err != nil {
+			s.lock.Unlock()
+			return err
+		}
+
+		stream = newRTPStream(runner, s.params.SampleRate, s.jitterDepth, s.maxGap, s.decode)
+		stream.start()
+
+		s.streams[p.SSRC] = stream
+	}
+
+	stream.lastPacket = time.Now()
+
+	s.lock.Unlock()
+
+	// The stream decodes and queues on its own goroutine, so a slow runner only delays its own stream
+	stream.push(p)
+
+	return nil
+}
+
+// decode converts a packet to samples with the format of its payload type, returning no samples for other types
+func (s *RTPSource) decode(p *RTPPacket) ([]int16, int, error) {
+	format, ok := s.formats[p.PayloadType]
+
+	if !ok {
+		return nil, 0, nil
+	}
+
+	return format.decode(p.Payload), format.SampleRate, nil
+}
+
+// expire closes streams which have timed out
+func (s *RTPSource) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for ssrc, stream := range s.streams {
+		if time.Since(stream.lastPacket) > s.streamTimeout {
+			stream.close()
+			delete(s.streams, ssrc)
+		}
+	}
+}
+
+const (
+	// streamQueueSize is how many packets may wait for a stream's runner before they're dropped, 1s of 20ms packets
+	streamQueueSize = 50
+)
+
+// rtpDecodeFunc decodes a packet to mono samples at the returned sample rate.
+// Packets without audio return no samples and no error.
+type rtpDecodeFunc func(p *RTPPacket) ([]int16, int, error)
+
+// newRTPStream creates a stream, which reorders and decodes packets for runner on its own goroutine once started
+func newRTPStream(runner *Runner, sampleRate, jitterDepth int, maxGap time.Duration, decode rtpDecodeFunc) *rtpStream {
+	return &rtpStream{
+		runner:     runner,
+		jitter:     newJitterBuffer(jitterDepth),
+		decode:     decode,
+		sampleRate: sampleRate,
+		maxGap:     maxGap,
+		packetCh:   make(chan *RTPPacket, streamQueueSize),
+		done:       make(chan bool),
+		lock:       new(sync.Mutex),
+	}
+}
+
+// rtpStream is the state of a single SSRC
+type rtpStream struct {
+	runner     *Runner
+	jitter     *jitterBuffer
+	decode     rtpDecodeFunc
+	sampleRate int
+	maxGap     time.Duration
+	// resampler converts from clockRate, the sample rate of the last packet with audio
+	resampler     *Resampler
+	clockRate     int
+	lastPacket    time.Time
+	started       bool
+	lastTimestamp uint32
+	// at is the time of the last packet relative to the first
+	at       time.Duration
+	packetCh chan *RTPPacket
+	// done is closed once the stream's goroutine has closed the runner
+	done   chan bool
+	closed bool
+	lock   *sync.Mutex
+}
+
+// start starts the stream's goroutine
+func (s *rtpStream) start() {
+	go s.run()
+}
+
+// push queues a packet for the stream's goroutine. A nil packet flushes the jitter buffer,
+// as the sender has paused, so there are no packets to wait for.
+// Packets are dropped if the runner has fallen behind, and are then handled as lost.
+func (s *rtpStream) push(p *RTPPacket) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.packetCh <- p:
+	default:
+	}
+}
+
+// run reorders and writes packets until the stream is closed, then flushes it and closes the runner
+func (s *rtpStream) run() {
+	for p := range s.packetCh {
+		if p == nil {
+			for _, ready := range s.jitter.flush() {
+				s.write(ready)
+			}
+
+			continue
+		}
+
+		for _, ready := range s.jitter.push(p) {
+			s.write(ready)
+		}
+	}
+
+	for _, p := range s.jitter.flush() {
+		s.write(p)
+	}
+
+	s.runner.Close()
+
+	close(s.done)
+}
+
+// write decodes a packet and queues it with its timestamp, leaving the runner to handle gaps.
+// Gaps, such as while the sender paused, are only filled up to maxGap.
+// Packets which fail to decode are treated as lost, and packets without audio are skipped.
+func (s *rtpStream) write(p *RTPPacket) {
+	samples, rate, err := s.decode(p)
+
+	if err != nil || samples == nil {
+		return
+	}
+
+	if s.resampler == nil || rate != s.clockRate {
+		s.resampler = NewResampler(rate, s.sampleRate)
+	}
+
+	if s.started {
+		s.at += time.Duration(int32(p.Timestamp-s.lastTimestamp)) * time.Second / time.Duration(rate)
+	}
+
+	s.started = true
+	s.clockRate = rate
+	s.lastTimestamp = p.Timestamp
+
+	out := s.resampler.Process(int16ToFloat32Slice(samples))
+
+	maxFill := s.runner.maxGapFill
+
+	if s.maxGap < maxFill {
+		maxFill = s.maxGap
+	}
+
+	if len(out) > 0 {
+		s.runner.queueAt(floatToInt16Slice(out), s.at, maxFill)
+	}
+}
+
+// close stops the stream, which flushes any waiting packets and closes its runner
+func (s *rtpStream) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	close(s.packetCh)
+}
@@ -0,0 +1,472 @@
+package precise
+
+import (
+	"encoding/binary"
+	"errors"
+	"gorgonia.org/tensor"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func rtpPacket(pt uint8, seq uint16, ts, ssrc uint32, payload []byte) []byte {
+	b := make([]byte, 12, 12+len(payload))
+
+	b[0] = 2 << 6
+	b[1] = pt
+	binary.BigEndian.PutUint16(b[2:4], seq)
+	binary.BigEndian.PutUint32(b[4:8], ts)
+	binary.BigEndian.PutUint32(b[8:12], ssrc)
+
+	return append(b, payload...)
+}
+
+func l16Payload(samples []int16) []byte {
+	b := make([]byte, len(samples)*2)
+
+	for i, s := range samples {
+		binary.BigEndian.PutUint16(b[i*2:], uint16(s))
+	}
+
+	return b
+}
+
+func TestParseRTP(t *testing.T) {
+	b := rtpPacket(0, 10, 160, 1234, []byte{1, 2, 3})
+
+	// Add a CSRC and padding
+	b[0] |= 0x20 | 1
+	b = append(b[:12], append([]byte{0, 0, 0, 9}, append(b[12:], 0, 2)...)...)
+
+	p, err := ParseRTP(b)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Sequence != 10 || p.Timestamp != 160 || p.SSRC != 1234 || len(p.Payload) != 3 || p.Payload[0] != 1 {
+		t.Fatal("unexpected packet", p)
+	}
+
+	if _, err := ParseRTP([]byte{0x80, 0}); err == nil {
+		t.Fatal("expected error for short packet")
+	}
+}
+
+func TestG711(t *testing.T) {
+	for _, c := range []struct {
+		in   byte
+		ulaw int16
+		alaw int16
+	}{
+		{0xFF, 0, 848},
+		{0x00, -32124, -5504},
+		{0x80, 32124, 5504},
+		{0xD5, 716, 8},
+		{0x55, -716, -8},
+	} {
+		if got := ulawToLinear(c.in); got != c.ulaw {
+			t.Errorf("ulaw %#x: expected %d, got %d", c.in, c.ulaw, got)
+		}
+
+		if got := alawToLinear(c.in); got != c.alaw {
+			t.Errorf("alaw %#x: expected %d, got %d", c.in, c.alaw, got)
+		}
+	}
+}
+
+func TestRTPSource(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := new(sync.Mutex)
+	runners := make(map[uint32]*Runner)
+
+	source := NewRTPSource(conn, NewParams(), func(id uint32) (*Runner, error) {
+		l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+			return 0, nil
+		}), NewParams())
+
+		if err != nil {
+			return nil, err
+		}
+
+		r := NewRunner(l, 320, WithPreRoll(time.Second))
+
+		lock.Lock()
+		runners[id] = r
+		lock.Unlock()
+
+		return r, nil
+	}, WithPayloadFormat(96, PayloadFormat{Encoding: EncodingL16, SampleRate: 16000, Channels: 1}))
+
+	done := make(chan error)
+
+	go func() {
+		done <- source.Serve()
+	}()
+
+	sender, err := net.Dial("udp", source.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sender.Close()
+
+	// Packets 0-4 of 320 samples, sent out of order with packet 3 lost
+	for _, seq := range []int{0, 2, 1, 4} {
+		for _, ssrc := range []uint32{1, 2} {
+			payload := l16Payload(sequence(seq*320+1, 320))
+
+			if _, err := sender.Write(rtpPacket(96, uint16(seq), uint32(seq*320), ssrc, payload)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Packet 3 is skipped once enough packets arrive after it
+	for seq := 5; seq < 12; seq++ {
+		for _, ssrc := range []uint32{1, 2} {
+			if _, err := sender.Write(rtpPacket(96, uint16(seq), uint32(seq*320), ssrc, l16Payload(sequence(seq*320+1, 320)))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		lock.Lock()
+		count := len(runners)
+
+		if count == 2 && len(runners[1].RecentAudio(time.Second)) >= 5*320 && len(runners[2].RecentAudio(time.Second)) >= 5*320 {
+			lock.Unlock()
+			break
+		}
+
+		lock.Unlock()
+
+		if time.Now().After(deadline) {
+			t.Fatal("streams did not receive audio")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, ssrc := range []uint32{1, 2} {
+		lock.Lock()
+		audio := runners[ssrc].RecentAudio(time.Second)
+		lock.Unlock()
+
+		// Packets 0-2 in order, then silence in place of packet 3
+		if !equalSamples(audio[:3*320], sequence(1, 3*320)) {
+			t.Fatal("packets were not reordered for stream", ssrc)
+		}
+
+		if !equalSamples(audio[3*320:4*320], make([]int16, 320)) {
+			t.Fatal("lost packet was not filled with silence for stream", ssrc)
+		}
+	}
+
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestRTPSource(t *testing.T, factory RunnerFactory, opts ...RTPOption) *RTPSource {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append(opts, WithPayloadFormat(96, PayloadFormat{Encoding: EncodingL16, SampleRate: 16000, Channels: 1}))
+
+	return NewRTPSource(conn, NewParams(), factory, opts...)
+}
+
+func newSilentRunner() (*Runner, error) {
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRunner(l, 320, WithPreRoll(time.Second)), nil
+}
+
+func waitRecentAudio(t *testing.T, r *Runner, n int) []int16 {
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		audio := r.RecentAudio(time.Second)
+
+		if len(audio) >= n {
+			return audio
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d samples, got %d", n, len(audio))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRTPSourceNonAudio(t *testing.T) {
+	var runner *Runner
+
+	source := newTestRTPSource(t, func(id uint32) (*Runner, error) {
+		r, err := newSilentRunner()
+		runner = r
+		return r, err
+	})
+
+	defer source.Close()
+
+	// A telephone event between audio packets doesn't leave a hole for the jitter buffer to wait on
+	for seq := 0; seq < 4; seq++ {
+		p := &RTPPacket{PayloadType: 96, Sequence: uint16(seq), Timestamp: uint32(seq * 320), SSRC: 1, Payload: l16Payload(sequence(seq*320+1, 320))}
+
+		if seq == 2 {
+			p.PayloadType = 101
+			p.Payload = []byte{1, 0, 0, 160}
+		}
+
+		if err := source.handle(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	audio := waitRecentAudio(t, runner, 4*320)
+
+	if !equalSamples(audio[:2*320], sequence(1, 2*320)) || !equalSamples(audio[3*320:4*320], sequence(3*320+1, 320)) {
+		t.Fatal("audio packets were not queued around the telephone event")
+	}
+}
+
+func TestRTPSourceStalledRunner(t *testing.T) {
+	runners := make(map[uint32]*Runner)
+
+	source := newTestRTPSource(t, func(id uint32) (*Runner, error) {
+		r, err := newSilentRunner()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// A closed runner never accepts samples
+		if id == 1 {
+			r.Close()
+		}
+
+		runners[id] = r
+
+		return r, nil
+	})
+
+	for seq := 0; seq < streamQueueSize*2; seq++ {
+		for _, ssrc := range []uint32{1, 2} {
+			p := &RTPPacket{PayloadType: 96, Sequence: uint16(seq), Timestamp: uint32(seq * 320), SSRC: ssrc, Payload: l16Payload(sequence(1, 320))}
+
+			if err := source.handle(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	waitRecentAudio(t, runners[2], 10*320)
+
+	source.lock.Lock()
+	stalled := source.streams[1]
+	source.lock.Unlock()
+
+	closed := make(chan error)
+
+	go func() {
+		closed <- source.Close()
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("close blocked on a stalled stream")
+	}
+
+	select {
+	case <-stalled.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stalled stream did not exit")
+	}
+}
+
+func TestRTPSourceStreamError(t *testing.T) {
+	lock := new(sync.Mutex)
+	runners := make(map[uint32]*Runner)
+
+	var failed []uint32
+
+	source := newTestRTPSource(t, func(id uint32) (*Runner, error) {
+		if id == 1 {
+			return nil, errors.New("no runner")
+		}
+
+		r, err := newSilentRunner()
+
+		if err != nil {
+			return nil, err
+		}
+
+		lock.Lock()
+		runners[id] = r
+		lock.Unlock()
+
+		return r, nil
+	}, WithStreamErrorFunc(func(id uint32, err error) {
+		lock.Lock()
+		failed = append(failed, id)
+		lock.Unlock()
+	}))
+
+	done := make(chan error)
+
+	go func() {
+		done <- source.Serve()
+	}()
+
+	sender, err := net.Dial("udp", source.Addr().String())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sender.Close()
+
+	// The stream which can't be created doesn't stop the other
+	for seq := 0; seq < 4; seq++ {
+		for _, ssrc := range []uint32{1, 2} {
+			if _, err := sender.Write(rtpPacket(96, uint16(seq), uint32(seq*320), ssrc, l16Payload(sequence(1, 320)))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		lock.Lock()
+		r := runners[2]
+		lock.Unlock()
+
+		if r != nil && len(r.RecentAudio(time.Second)) >= 4*320 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("stream 2 did not receive audio")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(failed) == 0 || failed[0] != 1 {
+		t.Fatal("expected stream 1 to fail, got", failed)
+	}
+
+	// No streams are created once the source is closed
+	if err := source.handle(&RTPPacket{PayloadType: 96, SSRC: 3, Payload: l16Payload(sequence(1, 320))}); err != nil {
+		t.Fatal(err)
+	}
+
+	if runners[3] != nil {
+		t.Fatal("expected no stream after close")
+	}
+}
+
+func TestRTPSourceMaxGap(t *testing.T) {
+	var runner *Runner
+
+	lock := new(sync.Mutex)
+
+	var discontinuities []Discontinuity
+
+	source := newTestRTPSource(t, func(id uint32) (*Runner, error) {
+		r, err := newSilentRunner()
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.OnDiscontinuity = func(d Discontinuity) {
+			lock.Lock()
+			discontinuities = append(discontinuities, d)
+			lock.Unlock()
+		}
+
+		runner = r
+
+		return r, nil
+	}, WithMaxGap(100*time.Millisecond))
+
+	defer source.Close()
+
+	// Half a second passes between the packets, which the runner would fill but is longer than the maximum gap
+	for i, ts := range []uint32{0, 8000} {
+		if err := source.handle(&RTPPacket{PayloadType: 96, Sequence: uint16(i), Timestamp: ts, SSRC: 1, Payload: l16Payload(sequence(1, 320))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		lock.Lock()
+		count := len(discontinuities)
+		lock.Unlock()
+
+		if count > 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expected the gap to be reported")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lock.Lock()
+	d := discontinuities[0]
+	lock.Unlock()
+
+	if d.Kind != DiscontinuityGap || !d.Reset || d.Filled != 0 || d.Length != 480*time.Millisecond {
+		t.Fatal("expected the gap to reset the feature window, got", d)
+	}
+
+	// Audio from before the gap isn't kept with the audio after it
+	if audio := waitRecentAudio(t, runner, 320); len(audio) != 320 {
+		t.Fatal("expected only the audio after the gap, got", len(audio), "samples")
+	}
+}
@@ -1,7 +1,11 @@
 package precise
 
 import (
+	"context"
+	"gorgonia.org/tensor"
 	"io"
+	"sync/atomic"
+	"time"
 )
 
 type ActivationFunc func()
@@ -26,6 +30,13 @@ func WithActivationFunc(f ActivationFunc) Option {
 	}
 }
 
+// WithActivationEventFunc sets the func called with details of each activation
+func WithActivationEventFunc(f ActivationEventFunc) Option {
+	return func(r *Runner) {
+		r.OnActivationEvent = f
+	}
+}
+
 // WithPredictionFunc sets the func called after prediction
 func WithPredictionFunc(f PredictionFunc) Option {
 	return func(r *Runner) {
@@ -33,20 +44,189 @@ func WithPredictionFunc(f PredictionFunc) Option {
 	}
 }
 
-// WithPredictionFunc sets the func called after prediction
+// WithExitFunc sets the func called when the runner exits
 func WithExitFunc(f ExitFunc) Option {
 	return func(r *Runner) {
 		r.OnExit = f
 	}
 }
 
+// WithVAD gates inference with a VAD, only running the model while speech is present
+func WithVAD(vad VAD, opts ...VADOption) Option {
+	return func(r *Runner) {
+		r.vad = vad
+		r.vadOpts = opts
+	}
+}
+
+// WithSpeechFunc sets the func called when the VAD speech state changes
+func WithSpeechFunc(f SpeechFunc) Option {
+	return func(r *Runner) {
+		r.OnSpeech = f
+	}
+}
+
+// WithCapture records the audio following an activation until the end of speech,
+// passing it to handler. The end of speech is detected by the VAD if one is set,
+// otherwise by a silence timeout.
+func WithCapture(handler UtteranceHandler, opts ...CaptureOption) Option {
+	return func(r *Runner) {
+		r.capture = newUtteranceCapture(handler, opts...)
+	}
+}
+
+// WithPreRoll keeps the last d of audio in memory, which is included with
+// activation events and available from RecentAudio
+func WithPreRoll(d time.Duration) Option {
+	return func(r *Runner) {
+		r.preRoll = d
+	}
+}
+
+// WithKeywordLocation estimates the start and end of the keyword for each
+// activation, using the pre-roll buffer. If no pre-roll is set, one long
+// enough to hold the model's buffer is used.
+func WithKeywordLocation(opts ...LocatorOption) Option {
+	return func(r *Runner) {
+		r.locatorOpts = opts
+		r.locate = true
+	}
+}
+
+// WithGapPolicy sets how gaps in audio passed to QueueAt are handled
+func WithGapPolicy(policy GapPolicy) Option {
+	return func(r *Runner) {
+		r.gapPolicy = policy
+	}
+}
+
+// WithMaxGapFill sets the longest gap filled with silence by GapFill, above which the feature window is reset
+func WithMaxGapFill(d time.Duration) Option {
+	return func(r *Runner) {
+		r.maxGapFill = d
+	}
+}
+
+// WithGapTolerance sets the largest timestamp difference which isn't treated as a gap or overlap
+func WithGapTolerance(d time.Duration) Option {
+	return func(r *Runner) {
+		r.gapTolerance = d
+	}
+}
+
+// WithDriftTolerance sets how far the sender's clock may drift from wall time, as a
+// fraction such as 0.02 for 2%, before it is reported
+func WithDriftTolerance(tolerance float64) Option {
+	return func(r *Runner) {
+		r.driftTolerance = tolerance
+	}
+}
+
+// WithDiscontinuityFunc sets the func called for each discontinuity handled by QueueAt
+func WithDiscontinuityFunc(f DiscontinuityFunc) Option {
+	return func(r *Runner) {
+		r.OnDiscontinuity = f
+	}
+}
+
+// WithPlaybackReference avoids activating on the application's own playback, which
+// is passed to Playback. EchoMute mutes detection during and after playback, while
+// EchoVeto vetoes activations whose audio matches the playback.
+func WithPlaybackReference(mode EchoMode, opts ...EchoOption) Option {
+	return func(r *Runner) {
+		r.echo = newEchoReference(mode, opts...)
+	}
+}
+
+// WithSelfTriggerFunc sets the func called when an activation is vetoed for matching playback
+func WithSelfTriggerFunc(f SelfTriggerFunc) Option {
+	return func(r *Runner) {
+		r.OnSelfTrigger = f
+	}
+}
+
+// WithBlacklist suppresses activations whose audio matches an entry in the blacklist
+func WithBlacklist(b *Blacklist) Option {
+	return func(r *Runner) {
+		r.blacklist = b
+	}
+}
+
+// WithBlacklistedFunc sets the func called when an activation is suppressed by the blacklist
+func WithBlacklistedFunc(f BlacklistedFunc) Option {
+	return func(r *Runner) {
+		r.OnBlacklisted = f
+	}
+}
+
+// WithBroadcastCoordinator reports activations to a coordinator shared by many runners,
+// which flags or suppresses them when many streams activate at once. The stream
+// identifies this runner to the coordinator, such as in CoLocate.
+func WithBroadcastCoordinator(c *BroadcastCoordinator, stream string) Option {
+	return func(r *Runner) {
+		r.broadcast = c
+		r.stream = stream
+	}
+}
+
+// WithArbiter reports activations to an arbiter, which picks a single winner when
+// several streams in a zone hear the same wake word. Activation handlers are only called
+// once this runner wins, and if it loses, it stops capturing or waiting for the command
+// that follows. The stream identifies this runner to the arbiter, such as in Zone.
+func WithArbiter(a *Arbiter, stream string) Option {
+	return func(r *Runner) {
+		r.arbiter = a
+		r.stream = stream
+	}
+}
+
+// WithComposer reports activations to a composer as detections of keyword, to be
+// combined with other runners' detections on the same stream into composite triggers
+func WithComposer(c *Composer, keyword string) Option {
+	return func(r *Runner) {
+		r.composer = c
+		r.keyword = keyword
+	}
+}
+
+// WithVerifier re-scores each activation with a second, usually larger, model before
+// it is emitted. Verification runs in the background, so audio keeps being processed.
+// The verifier must use the same sample rate, and is closed with the runner.
+func WithVerifier(verifier *Listener, opts ...VerifierOption) Option {
+	return func(r *Runner) {
+		r.verifier = newVerifier(verifier, opts...)
+	}
+}
+
+// WithVerificationFunc sets the func called with the result of each verification
+func WithVerificationFunc(f VerificationFunc) Option {
+	return func(r *Runner) {
+		r.OnVerification = f
+	}
+}
+
+// WithShadow scores a candidate model on the same features as the production model, on
+// its own goroutine with its own detector, without acting on its output. Disagreements are
+// reported to WithDisagreementFunc, and ShadowReport summarises the comparison.
+// A shadow model which can't use the production model's features isn't run, and ShadowReport returns the error.
+// The shadow model is closed with the runner.
+func WithShadow(model Model, opts ...ShadowOption) Option {
+	return func(r *Runner) {
+		r.shadow = newShadow(model, opts...)
+	}
+}
+
 // NewRunner creates a new network runner
 func NewRunner(listener *Listener, chunkSize int, opts ...Option) *Runner {
 	r := &Runner{
-		listener:  listener,
-		chunkSize: chunkSize,
-		sampleCh:  make(chan []int16),
-		closeCh:   make(chan bool),
+		listener:       listener,
+		chunkSize:      chunkSize,
+		maxGapFill:     time.Second,
+		gapTolerance:   5 * time.Millisecond,
+		driftTolerance: 0.02,
+		now:            time.Now,
+		sampleCh:       make(chan queuedChunk),
+		closeCh:        make(chan bool),
 	}
 
 	for _, opt := range opts {
@@ -55,70 +235,256 @@ func NewRunner(listener *Listener, chunkSize int, opts ...Option) *Runner {
 
 	r.detector = NewTriggerDetector(chunkSize, r.detectorOpts...)
 
+	if r.locate {
+		r.locator = newKeywordLocator(listener.params, r.locatorOpts...)
+
+		if minPreRoll := time.Duration(2 * float64(listener.params.BufferT) * float64(time.Second)); r.preRoll < minPreRoll {
+			r.preRoll = minPreRoll
+		}
+	}
+
+	if r.echo != nil {
+		bufferT := time.Duration(float64(listener.params.BufferT) * float64(time.Second))
+
+		r.echo.init(listener.params.SampleRate, bufferT)
+
+		// Vetoes compare the last BufferT of audio with the playback
+		if r.echo.mode == EchoVeto && r.preRoll < bufferT {
+			r.preRoll = bufferT
+		}
+	}
+
+	if r.arbiter != nil {
+		// Arbitration compares the energy of the last BufferT of audio
+		if bufferT := time.Duration(float64(listener.params.BufferT) * float64(time.Second)); r.preRoll < bufferT {
+			r.preRoll = bufferT
+		}
+
+		r.arbitratedCh = make(chan Activation)
+		r.arbiter.register(r.stream, r)
+	}
+
+	if r.composer != nil {
+		r.composer.register(r)
+	}
+
+	if r.verifier != nil {
+		r.verifyCh = make(chan verifiedActivation)
+
+		// The verifier scores its own window, and the command heard while verifying is passed to any capture
+		window := time.Duration(float64(r.verifier.listener.params.BufferT)*float64(time.Second)) + r.verifier.timeout
+
+		if r.preRoll < window {
+			r.preRoll = window
+		}
+	}
+
+	if r.blacklist != nil {
+		// Blacklisted activations are matched on the last BufferT of audio
+		if bufferT := time.Duration(float64(listener.params.BufferT) * float64(time.Second)); r.preRoll < bufferT {
+			r.preRoll = bufferT
+		}
+	}
+
+	if r.shadow != nil && r.shadow.audio > 0 {
+		// Disagreements are settled once both models are past the tolerance, and the shadow may be behind
+		window := r.shadow.audio + r.shadow.tolerance + r.duration(chunkSize*(r.shadow.budget+1))
+
+		if r.preRoll < window {
+			r.preRoll = window
+		}
+	}
+
+	if r.preRoll > 0 {
+		r.recent = newSampleRing(r.samples(r.preRoll))
+	}
+
+	if r.shadow != nil {
+		r.shadow.start(listener.params, chunkSize, r.recent)
+	}
+
+	if r.vad != nil {
+		r.gate = NewSpeechGate(r.vad, listener.params.SampleRate, r.vadOpts...)
+	}
+
+	if r.capture != nil {
+		// Utterances are handled after the runner moves on, so their context is cancelled when it closes
+		r.capture.ctx, r.cancelCapture = context.WithCancel(context.Background())
+	}
+
 	r.Start()
 
 	return r
 }
 
 type Runner struct {
-	listener     *Listener
-	detector     *TriggerDetector
-	detectorOpts []TriggerOption
-	chunkSize    int
-	running      bool
-	sampleCh     chan []int16
-	closeCh      chan bool
-
-	OnPrediction PredictionFunc
-	OnActivation ActivationFunc
-	OnExit       ExitFunc
+	listener       *Listener
+	detector       *TriggerDetector
+	detectorOpts   []TriggerOption
+	vad            VAD
+	vadOpts        []VADOption
+	gate           *SpeechGate
+	awaitCommand   bool
+	commandSpeech  int
+	capture        *utteranceCapture
+	cancelCapture  context.CancelFunc
+	preRoll        time.Duration
+	recent         *sampleRing
+	locate         bool
+	locatorOpts    []LocatorOption
+	locator        *keywordLocator
+	position       int64
+	gapPolicy      GapPolicy
+	maxGapFill     time.Duration
+	gapTolerance   time.Duration
+	driftTolerance float64
+	clock          streamClock
+	echo           *echoReference
+	blacklist      *Blacklist
+	broadcast      *BroadcastCoordinator
+	arbiter        *Arbiter
+	arbitratedCh   chan Activation
+	composer       *Composer
+	verifier       *verifier
+	verifying      bool
+	verifyCh       chan verifiedActivation
+	shadow         *shadow
+	keyword        string
+	ignore         atomic.Bool
+	stream         string
+	now            func() time.Time
+	chunkSize      int
+	running        atomic.Bool
+	closed         atomic.Bool
+	done           atomic.Value // chan bool closed when the goroutine started last exits
+	sampleCh       chan queuedChunk
+	closeCh        chan bool
+
+	OnPrediction      PredictionFunc
+	OnActivation      ActivationFunc
+	OnActivationEvent ActivationEventFunc
+	OnSpeech          SpeechFunc
+	OnDiscontinuity   DiscontinuityFunc
+	OnSelfTrigger     SelfTriggerFunc
+	OnBlacklisted     BlacklistedFunc
+	OnVerification    VerificationFunc
+	OnExit            ExitFunc
 }
 
 // Start will start the runner and the goroutine
 func (r *Runner) Start() {
-	if r.running {
+	if !r.running.CompareAndSwap(false, true) {
 		return
 	}
 
-	r.running = true
+	done := make(chan bool)
+	r.done.Store(done)
 
-	go r.handlePredictions()
+	go r.handlePredictions(done)
 }
 
 // Stop will stop the runner without closing it.
 func (r *Runner) Stop() {
-	if !r.running {
-		return
-	}
-
-	r.running = false
+	r.running.Store(false)
 }
 
-// Close stops the neural network runner
+// Close stops the neural network runner. Closing it again does nothing.
 func (r *Runner) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
 	r.Stop()
 
 	close(r.closeCh)
 
+	if r.cancelCapture != nil {
+		r.cancelCapture()
+	}
+
+	if r.arbiter != nil {
+		r.arbiter.unregister(r.stream, r)
+	}
+
+	if r.composer != nil {
+		r.composer.unregister(r)
+	}
+
+	// Everything is closed even if something fails, returning the first error
+	var first error
+
+	if r.gate != nil {
+		if err := r.gate.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if r.shadow != nil {
+		if err := r.shadow.close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	if r.verifier != nil {
+		if err := r.verifier.listener.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
 	if r.listener != nil {
-		return r.listener.Close()
+		if err := r.listener.Close(); err != nil && first == nil {
+			first = err
+		}
 	}
 
-	return nil
+	return first
+}
+
+// SwapModel replaces the runner's model without stopping it, keeping the feature window.
+// See Listener.SetModel.
+func (r *Runner) SwapModel(model Model) error {
+	return r.listener.SetModel(model)
 }
 
 // Write allows a Runner to act as an io.Writer
 func (r *Runner) Write(b []byte) (int, error) {
 	samples := bytesToSamples(b)
 
-	r.sampleCh <- samples
+	r.sampleCh <- queuedChunk{samples: samples}
 
 	return len(b) % 2, nil
 }
 
 // Queue passes in samples directly to the channel
 func (r *Runner) Queue(samples []int16) {
-	r.sampleCh <- samples
+	r.sampleCh <- queuedChunk{samples: samples}
+}
+
+// queue passes a chunk to the runner's goroutine, or drops it if the runner is closed
+func (r *Runner) queue(chunk queuedChunk) {
+	select {
+	case r.sampleCh <- chunk:
+	case <-r.closeCh:
+	}
+}
+
+// Playback passes in audio being played by the application, at the model sample rate,
+// as a reference for WithPlaybackReference. Playback should be passed as it is played.
+func (r *Runner) Playback(samples []int16) {
+	if r.echo == nil {
+		return
+	}
+
+	r.echo.write(samples, r.now())
+}
+
+// RecentAudio returns a copy of up to the last d of audio from the pre-roll buffer
+func (r *Runner) RecentAudio(d time.Duration) []int16 {
+	if r.recent == nil {
+		return nil
+	}
+
+	return r.recent.Last(r.samples(d))
 }
 
 // ReadFrom allows the Runner to simply read from a reader
@@ -126,7 +492,7 @@ func (r *Runner) ReadFrom(reader io.Reader) (int64, error) {
 	chunkSize := r.chunkSize
 
 	if chunkSize == 0 || chunkSize == -1 {
-		chunkSize = 2048
+		chunkSize = defaultChunkSize
 	}
 
 	buf := make([]byte, chunkSize)
@@ -144,49 +510,381 @@ func (r *Runner) ReadFrom(reader io.Reader) (int64, error) {
 
 		total += int64(read)
 
-		r.sampleCh <- bytesToSamples(buf[:read])
+		r.sampleCh <- queuedChunk{samples: bytesToSamples(buf[:read])}
 	}
 
 	return total, nil
 }
 
-// handlePredictions is a constantly running goroutine to read samples from our chan
-func (r *Runner) handlePredictions() {
-	var prob float32
+// handlePredictions is a constantly running goroutine to read samples from our chan, closing done when it exits
+func (r *Runner) handlePredictions(done chan bool) {
+	defer close(done)
+
 	var err error
 
 loop:
-	for r.running {
+	for r.running.Load() {
 		select {
-		case samples, ok := <-r.sampleCh:
+		case chunk, ok := <-r.sampleCh:
 			if !ok {
 				break loop
 			}
-			prob, err = r.listener.Update(samples)
 
-			if err != nil {
-				break
-			}
+			r.handleDiscontinuities(chunk.discontinuities)
 
-			if r.OnPrediction != nil {
-				r.OnPrediction(prob)
-			}
+			if len(chunk.samples) > 0 {
+				err = r.process(chunk.samples)
 
-			if r.detector.Update(prob) {
-				r.OnActivation()
+				if r.composer != nil {
+					r.composer.advance(r, r.duration(int(r.position)))
+				}
 			}
+		case res := <-r.verifyCh:
+			err = r.verified(res)
+		case a := <-r.arbitratedCh:
+			r.activated(a)
 		case <-r.closeCh:
 			break loop
 		}
 	}
 
-	r.running = false
+	r.running.Store(false)
+
+	if r.capture != nil && r.capture.active {
+		r.capture.finish(EndClosed)
+	}
 
 	if r.OnExit != nil {
 		r.OnExit(err)
 	}
 }
 
+// process runs a single chunk of samples through the VAD, capture and model
+func (r *Runner) process(samples []int16) error {
+	r.position += int64(len(samples))
+
+	// Another stream won arbitration for the last activation
+	if r.ignore.Swap(false) {
+		r.awaitCommand = false
+
+		if r.capture != nil {
+			r.capture.cancel()
+		}
+	}
+
+	if r.recent != nil {
+		r.recent.Write(samples)
+	}
+
+	event, err := r.updateGate(samples)
+
+	if err != nil {
+		return err
+	}
+
+	if r.capture != nil && r.capture.active {
+		r.listener.Skip(samples)
+
+		if reason, done := r.capture.add(samples, event, r.gate); done {
+			r.capture.finish(reason)
+		}
+
+		return nil
+	}
+
+	var prob float32
+	var features tensor.Tensor
+
+	if r.gate != nil && !r.gate.Speaking() && event == SpeechNone || r.echo != nil && r.echo.muted(r.now()) {
+		// Skip inference when the VAD reports no speech, or during playback
+		r.listener.Skip(samples)
+	} else {
+		prob, features, err = r.listener.update(samples)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.OnPrediction != nil {
+		r.OnPrediction(prob)
+	}
+
+	if r.locator != nil {
+		r.locator.add(r.position, prob)
+	}
+
+	activated := r.detector.Update(prob)
+
+	if r.shadow != nil {
+		r.shadow.add(shadowChunk{
+			features:        features,
+			time:            r.now(),
+			position:        r.position,
+			production:      prob,
+			productionAbove: prob > 1-r.detector.sensitivity,
+			productionFired: activated,
+		})
+	}
+
+	if activated {
+		return r.activate(prob)
+	}
+
+	return nil
+}
+
+// ShadowReport returns how the shadow model has compared with the production model, if WithShadow is used
+func (r *Runner) ShadowReport() ShadowReport {
+	if r.shadow == nil {
+		return ShadowReport{}
+	}
+
+	return r.shadow.Report()
+}
+
+// updateGate passes samples to the VAD, if any, and emits speech events
+func (r *Runner) updateGate(samples []int16) (SpeechEvent, error) {
+	if r.gate == nil {
+		return SpeechNone, nil
+	}
+
+	event, err := r.gate.Update(samples)
+
+	if err != nil {
+		return SpeechNone, err
+	}
+
+	// The end of speech only ends the command once the command has been spoken,
+	// rather than at the pause after the wake word
+	if r.awaitCommand && r.gate.voiced {
+		r.commandSpeech += len(samples)
+	}
+
+	if event == SpeechEnd && r.awaitCommand && r.commandSpeech > 0 && r.commandSpeech >= r.gate.samples(r.gate.minSpeech) {
+		event = CommandEnd
+		r.awaitCommand = false
+	}
+
+	if event != SpeechNone && r.OnSpeech != nil {
+		r.OnSpeech(event)
+	}
+
+	return event, nil
+}
+
+// activate is called when the detector has triggered
+func (r *Runner) activate(prob float32) error {
+	if r.echo != nil && r.echo.mode == EchoVeto {
+		audio := r.recent.Last(r.listener.params.BufferSamples())
+
+		if c := r.echo.correlation(audio, r.now()); c >= r.echo.threshold {
+			if r.OnSelfTrigger == nil {
+				return nil
+			}
+
+			a, err := r.activation(prob)
+
+			if err != nil {
+				return err
+			}
+
+			r.OnSelfTrigger(a, c)
+
+			return nil
+		}
+	}
+
+	if r.blacklist != nil {
+		audio := r.recent.Last(r.listener.params.BufferSamples())
+
+		if entry, similarity, ok := r.blacklist.Match(NewFingerprint(audio, r.listener.params.SampleRate)); ok {
+			if r.OnBlacklisted == nil {
+				return nil
+			}
+
+			a, err := r.activation(prob)
+
+			if err != nil {
+				return err
+			}
+
+			r.OnBlacklisted(a, entry, similarity)
+
+			return nil
+		}
+	}
+
+	if r.verifier != nil {
+		// Triggers while an activation is being verified are part of the same wake word
+		if r.verifying {
+			return nil
+		}
+
+		a, err := r.activation(prob)
+
+		if err != nil {
+			return err
+		}
+
+		r.verifying = true
+
+		go r.verifier.verify(a, r.recent.Last(r.verifier.listener.params.BufferSamples()), r.verifyCh, r.closeCh)
+
+		return nil
+	}
+
+	return r.emit(func() (Activation, error) {
+		return r.activation(prob)
+	})
+}
+
+// verified emits an activation once the verifier accepts it
+func (r *Runner) verified(res verifiedActivation) error {
+	r.verifying = false
+
+	if res.err != nil {
+		return res.err
+	}
+
+	if r.OnVerification != nil {
+		r.OnVerification(res.activation, res.verification)
+	}
+
+	if !res.verification.Accepted {
+		return nil
+	}
+
+	if err := r.emit(func() (Activation, error) {
+		return res.activation, nil
+	}); err != nil {
+		return err
+	}
+
+	// Audio heard while verifying is the start of the command
+	if r.capture != nil && r.capture.active {
+		r.capture.add(r.recent.Last(int(r.position-res.activation.Position)), SpeechNone, nil)
+	}
+
+	return nil
+}
+
+// emit passes an activation through any coordinators to the handlers.
+// describe is only called if the activation is needed.
+func (r *Runner) emit(describe func() (Activation, error)) error {
+	var a Activation
+
+	if r.broadcast != nil || r.arbiter != nil || r.composer != nil || r.OnActivationEvent != nil {
+		var err error
+
+		a, err = describe()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.broadcast != nil && r.broadcast.report(r.stream, a) {
+		if r.broadcast.action == BroadcastSuppress {
+			return nil
+		}
+
+		a.Broadcast = true
+	}
+
+	if r.composer != nil {
+		r.composer.report(r.keyword, a)
+	}
+
+	r.awaitCommand = r.gate != nil
+	r.commandSpeech = 0
+
+	if r.capture != nil {
+		r.capture.begin(r.listener.params.SampleRate)
+	}
+
+	// The handlers are called if the arbiter picks this runner, and the capture cancelled if not
+	if r.arbiter != nil {
+		audio := a.Audio
+
+		if a.Keyword.End > a.Keyword.Start {
+			audio = a.KeywordAudio()
+		} else if n := r.listener.params.BufferSamples(); len(audio) > n {
+			audio = audio[len(audio)-n:]
+		}
+
+		r.arbiter.report(r.stream, a, rms(audio))
+
+		return nil
+	}
+
+	r.activated(a)
+
+	return nil
+}
+
+// won passes the activation which won arbitration to the runner's goroutine, unless the runner is closed,
+// or stopped and its goroutine has exited
+func (r *Runner) won(a Activation) {
+	done, _ := r.done.Load().(chan bool)
+
+	select {
+	case r.arbitratedCh <- a:
+	case <-r.closeCh:
+	case <-done:
+	}
+}
+
+// activated calls the activation handlers
+func (r *Runner) activated(a Activation) {
+	if r.OnActivation != nil {
+		r.OnActivation()
+	}
+
+	if r.OnActivationEvent == nil {
+		return
+	}
+
+	r.OnActivationEvent(a)
+}
+
+// activation describes the activation triggered by the current chunk
+func (r *Runner) activation(prob float32) (Activation, error) {
+	a := Activation{
+		Time:        time.Now(),
+		Probability: prob,
+		SampleRate:  r.listener.params.SampleRate,
+		Position:    r.position,
+	}
+
+	if r.recent != nil {
+		a.Audio = r.recent.Last(r.samples(r.preRoll))
+	}
+
+	if r.locator != nil {
+		threshold := 1.0 - r.detector.sensitivity
+
+		a.Keyword = r.locator.locate(a.Audio, a.Position, threshold)
+
+		if r.locator.refine {
+			var err error
+
+			a.Keyword, err = r.locator.refineEnd(r.listener, a.Audio, a.Position, a.Keyword, threshold)
+
+			if err != nil {
+				return a, err
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// samples converts a duration to a number of samples at the model sample rate
+func (r *Runner) samples(d time.Duration) int {
+	return int(d.Seconds() * float64(r.listener.params.SampleRate))
+}
+
 // bytesToSamples converts bytes to 16-bit samples
 func bytesToSamples(b []byte) []int16 {
 	readable := len(b) / 2
@@ -0,0 +1,119 @@
+//go:build onnx && cgo
+
+package precise
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewRunner(t *testing.T) {
+	testRunModel(t, "out.wav")
+}
+
+func testRunModel(t *testing.T, inputFile string) {
+	model, err := NewONNXModel("astra.onnx", OnnxCUDA)
+
+	if err != nil {
+		t.Fatal("Unable to load model")
+	}
+
+	p := NewParams()
+
+	l, err := NewListener(model, p)
+
+	t.Log("Testing file", inputFile)
+
+	samples, err := loadSamples(inputFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activated := false
+
+	ch := make(chan struct{})
+
+	t.Log("Setting up runner")
+
+	var runner *Runner
+
+	opts := []Option{
+		WithActivationFunc(func() {
+			activated = true
+		}),
+		WithExitFunc(func(err error) {
+			close(ch)
+
+			runner.Close()
+		}),
+		WithDetectorOpts(WithSensitivity(0.8)),
+	}
+
+	runner = NewRunner(l, -1, opts...)
+
+	t.Log("Queueing samples")
+
+	for i := 0; i < len(samples); i += defaultChunkSize / 2 {
+		end := i + defaultChunkSize/2
+
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		runner.Queue(samples[i:end])
+	}
+
+	t.Log("Successfully queued", len(samples), "samples")
+
+	runner.Stop()
+
+	<-ch
+
+	if activated {
+		t.Log("Sample activated")
+	} else {
+		t.Log("No activation found")
+	}
+}
+
+func BenchmarkOnnxRunner(b *testing.B) {
+	for t := OnnxCPU; t <= OnnxCUDA; t++ {
+		b.Run(fmt.Sprintf("onnx_%s", t.String()), func(b *testing.B) {
+			b.StopTimer()
+			model, err := NewONNXModel("astra.onnx", t)
+
+			if err != nil {
+				b.Fatal("Unable to load model")
+			}
+
+			p := NewParams()
+
+			l, err := NewListener(model, p)
+
+			defer l.Close()
+
+			samples, err := loadSamples("out.wav")
+
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			mfccs := l.updateVectors(samples)
+
+			var val float32
+
+			b.StartTimer()
+
+			for i := 0; i < b.N; i++ {
+				val, err = l.model.Predict(mfccs)
+
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			benchResult = val
+		})
+	}
+}
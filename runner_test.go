@@ -1,205 +1,109 @@
 package precise
 
 import (
-	"fmt"
-	"github.com/cryptix/wav"
-	"os"
+	"gorgonia.org/tensor"
+	"math"
+	"math/rand"
+	"sync"
 	"testing"
+	"time"
 )
 
-func TestNewRunner(t *testing.T) {
-	testRunModel(t, "out.wav")
-}
-
-func testRunModel(t *testing.T, inputFile string) {
-	model, err := NewONNXModel("astra.onnx", OnnxCUDA)
-
-	if err != nil {
-		t.Fatal("Unable to load model")
-	}
-
-	p := NewParams()
-
-	l, err := NewListener(model, p)
-
-	t.Log("Testing file", inputFile)
-
-	f, err := os.Open(inputFile)
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	stat, err := f.Stat()
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	wr, err := wav.NewReader(f, stat.Size())
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	wdr, err := wr.GetDumbReader()
-
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	activated := false
-
-	ch := make(chan struct{})
-
-	t.Log("Setting up runner")
-
-	var runner *Runner
-
-	opts := []Option{
-		WithActivationFunc(func() {
-			activated = true
-		}),
-		WithExitFunc(func(err error) {
-			close(ch)
-
-			runner.Close()
-		}),
-		WithDetectorOpts(WithSensitivity(0.8)),
-	}
-
-	runner = NewRunner(l, -1, opts...)
-
-	t.Log("Reading data")
+var benchResult float32
 
-	read, err := runner.ReadFrom(wdr)
+func loadSamples(inputFile string) ([]int16, error) {
+	audio, err := ReadWAVFile(inputFile)
 
 	if err != nil {
-		t.Fatal("Unable to read wav data", err)
-	} else {
-		t.Log("Successfully read", read, "bytes")
+		return nil, err
 	}
 
-	runner.Stop()
-
-	<-ch
-
-	if activated {
-		t.Log("Sample activated")
-	} else {
-		t.Log("No activation found")
-	}
+	return ConvertAudio(audio, NewParams())
 }
 
-var benchResult float32
-
-func BenchmarkTFLiteRunner(b *testing.B) {
-	model, err := NewTFLiteModel("astra.tflite")
-
-	if err != nil {
-		b.Fatal("Unable to load model")
-	}
-
-	p := NewParams()
+// fakeClock is a clock advanced by tests
+type fakeClock struct {
+	lock *sync.Mutex
+	now  time.Time
+}
 
-	l, err := NewListener(model, p)
+func newFakeClock() *fakeClock {
+	return &fakeClock{lock: new(sync.Mutex), now: time.Now()}
+}
 
-	defer l.Close()
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	samples, err := loadSamples("out.wav")
+	return c.now
+}
 
-	if err != nil {
-		b.Fatal(err)
-	}
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	c.lock.Unlock()
+}
 
-	mfccs := l.updateVectors(samples)
+// modulatedTone creates a 500Hz tone with a random level every 50ms, like speech or music
+func modulatedTone(seed int64, n int) []int16 {
+	r := rand.New(rand.NewSource(seed))
 
-	var val float32
+	samples := make([]int16, n)
 
-	for i := 0; i < b.N; i++ {
-		val, err = l.model.Predict(mfccs)
+	var level float64
 
-		if err != nil {
-			b.Fatal(err)
+	for i := range samples {
+		if i%800 == 0 {
+			level = r.Float64() * 0.3
 		}
+
+		samples[i] = int16(level * 32767 * math.Sin(2*math.Pi*500*float64(i)/16000))
 	}
 
-	benchResult = val
+	return samples
 }
 
-func BenchmarkOnnxRunner(b *testing.B) {
-	for t := OnnxCPU; t <= OnnxCUDA; t++ {
-		b.Run(fmt.Sprintf("onnx_%s", t.String()), func(b *testing.B) {
-			b.StopTimer()
-			model, err := NewONNXModel("astra.onnx", t)
-
-			if err != nil {
-				b.Fatal("Unable to load model")
-			}
-
-			p := NewParams()
-
-			l, err := NewListener(model, p)
-
-			defer l.Close()
-
-			samples, err := loadSamples("out.wav")
-
-			if err != nil {
-				b.Fatal(err)
-			}
+// newTriggeringRunner creates a runner whose model triggers from two seconds in, returning a func
+// which waits for the queued audio to be processed and returns the number of activations
+func newTriggeringRunner(t *testing.T, opts ...Option) (*Runner, func() int) {
+	calls := 0
 
-			mfccs := l.updateVectors(samples)
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
 
-			var val float32
-
-			b.StartTimer()
-
-			for i := 0; i < b.N; i++ {
-				val, err = l.model.Predict(mfccs)
-
-				if err != nil {
-					b.Fatal(err)
-				}
-			}
-
-			benchResult = val
-		})
-	}
-}
+		if calls > 100 {
+			return 1, nil
+		}
 
-func loadSamples(inputFile string) ([]int16, error) {
-	f, err := os.Open(inputFile)
+		return 0, nil
+	}), NewParams())
 
 	if err != nil {
-		return nil, err
+		t.Fatal(err)
 	}
 
-	defer f.Close()
+	lock := new(sync.Mutex)
+	activations := 0
 
-	stat, err := f.Stat()
+	opts = append(opts, WithActivationFunc(func() {
+		lock.Lock()
+		activations++
+		lock.Unlock()
+	}))
 
-	if err != nil {
-		return nil, err
-	}
-
-	wr, err := wav.NewReader(f, stat.Size())
-
-	if err != nil {
-		return nil, err
-	}
+	r := NewRunner(l, 320, opts...)
 
-	samples := make([]int16, wr.GetSampleCount())
+	t.Cleanup(func() {
+		r.Close()
+	})
 
-	for i := 0; i < len(samples); i++ {
-		sample, err := wr.ReadSample()
+	return r, func() int {
+		// Wait for the last chunk to be processed
+		r.Queue(nil)
 
-		if err != nil {
-			return nil, err
-		}
+		lock.Lock()
+		defer lock.Unlock()
 
-		samples[i] = int16(sample)
+		return activations
 	}
-
-	return samples, nil
 }
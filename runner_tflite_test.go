@@ -0,0 +1,39 @@
+//go:build cgo && !notflite
+
+package precise
+
+import "testing"
+
+func BenchmarkTFLiteRunner(b *testing.B) {
+	model, err := NewTFLiteModel("astra.tflite")
+
+	if err != nil {
+		b.Fatal("Unable to load model")
+	}
+
+	p := NewParams()
+
+	l, err := NewListener(model, p)
+
+	defer l.Close()
+
+	samples, err := loadSamples("out.wav")
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	mfccs := l.updateVectors(samples)
+
+	var val float32
+
+	for i := 0; i < b.N; i++ {
+		val, err = l.model.Predict(mfccs)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	benchResult = val
+}
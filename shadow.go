@@ -0,0 +1,416 @@
+package precise
+
+import (
+	"fmt"
+	"gorgonia.org/tensor"
+	"sync"
+	"time"
+)
+
+// DisagreementKind is which model activated without the other
+type DisagreementKind int
+
+const (
+	// ProductionOnly is an activation by the production model which the shadow model didn't match
+	ProductionOnly DisagreementKind = iota
+	// ShadowOnly is an activation by the shadow model which the production model didn't match
+	ShadowOnly
+)
+
+func (k DisagreementKind) String() string {
+	switch k {
+	case ProductionOnly:
+		return "ProductionOnly"
+	case ShadowOnly:
+		return "ShadowOnly"
+	}
+	return ""
+}
+
+// Disagreement is an activation only one of the production and shadow models produced
+type Disagreement struct {
+	Kind DisagreementKind
+	// Time is when the chunk which activated was processed, and Position its end in the stream, in samples
+	Time     time.Time
+	Position int64
+	// ProductionPeak and ShadowPeak are each model's highest score within the tolerance of the activation
+	ProductionPeak float32
+	ShadowPeak     float32
+	// Audio ends at the activation, if enabled by WithShadowAudio
+	Audio []int16
+}
+
+// DisagreementFunc is called with each disagreement between the production and shadow models
+type DisagreementFunc func(d Disagreement)
+
+// ShadowReport summarises how a shadow model compared with the production model
+type ShadowReport struct {
+	// Chunks were scored by both models, and ChunksAgreed had both above or both below their detector's threshold
+	Chunks       int
+	ChunksAgreed int
+	// Dropped are chunks the shadow model skipped to stay within its budget
+	Dropped               int
+	ProductionActivations int
+	ShadowActivations     int
+	// Agreed are activations by both models within the tolerance of each other
+	Agreed         int
+	ProductionOnly int
+	ShadowOnly     int
+	// Err is why the shadow model isn't being run, such as params incompatible with the production model's
+	Err error
+}
+
+// ChunkAgreementRate returns the fraction of chunks where both models agreed
+func (r ShadowReport) ChunkAgreementRate() float64 {
+	if r.Chunks == 0 {
+		return 0
+	}
+
+	return float64(r.ChunksAgreed) / float64(r.Chunks)
+}
+
+// AgreementRate returns the fraction of activations, by either model, which both models produced
+func (r ShadowReport) AgreementRate() float64 {
+	total := r.Agreed + r.ProductionOnly + r.ShadowOnly
+
+	if total == 0 {
+		return 1
+	}
+
+	return float64(r.Agreed) / float64(total)
+}
+
+type ShadowOption func(*shadow)
+
+// WithShadowParams sets the params used to decode the shadow model's output, which must
+// be compatible with the production model's. The production params are used by default.
+func WithShadowParams(p Params) ShadowOption {
+	return func(s *shadow) {
+		s.params = &p
+	}
+}
+
+// WithShadowDetector sets the options for the shadow model's own detector
+func WithShadowDetector(opts ...TriggerOption) ShadowOption {
+	return func(s *shadow) {
+		s.detectorOpts = opts
+	}
+}
+
+// WithShadowBudget sets how many chunks may wait for the shadow model before chunks are dropped
+func WithShadowBudget(chunks int) ShadowOption {
+	return func(s *shadow) {
+		s.budget = chunks
+	}
+}
+
+// WithShadowTolerance sets how far apart activations by each model may be to agree
+func WithShadowTolerance(d time.Duration) ShadowOption {
+	return func(s *shadow) {
+		s.tolerance = d
+	}
+}
+
+// WithShadowAudio includes the given length of audio, ending at the activation, in disagreements
+func WithShadowAudio(d time.Duration) ShadowOption {
+	return func(s *shadow) {
+		s.audio = d
+	}
+}
+
+// WithDisagreementFunc sets the func called with each disagreement
+func WithDisagreementFunc(f DisagreementFunc) ShadowOption {
+	return func(s *shadow) {
+		s.OnDisagreement = f
+	}
+}
+
+// newShadow creates a shadow for a model, which is started by start
+func newShadow(model Model, opts ...ShadowOption) *shadow {
+	s := &shadow{
+		model:     model,
+		budget:    10,
+		tolerance: time.Second,
+		lock:      new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// shadow runs a candidate model on the production model's features, on its own goroutine,
+// without acting on its output
+type shadow struct {
+	model        Model
+	params       *Params
+	detectorOpts []TriggerOption
+	budget       int
+	tolerance    time.Duration
+	audio        time.Duration
+	sampleRate   int
+	decoder      *ThresholdDecoder
+	detector     *TriggerDetector
+	recent       *sampleRing
+	chunkCh      chan shadowChunk
+	doneCh       chan bool
+	closed       bool
+	// scores are the recent chunks scored by both models, and production and candidates the unmatched activations
+	scores     []shadowChunk
+	production []shadowChunk
+	candidates []shadowChunk
+	report     ShadowReport
+	lock       *sync.Mutex
+
+	OnDisagreement DisagreementFunc
+}
+
+// shadowChunk is a chunk of features, with the production model's result
+type shadowChunk struct {
+	features        tensor.Tensor
+	time            time.Time
+	position        int64
+	production      float32
+	productionAbove bool
+	productionFired bool
+	shadow          float32
+}
+
+// start creates the decoder and detector, and starts the goroutine scoring chunks.
+// A shadow model which can't use the production model's features isn't started, and the error is kept for the report.
+func (s *shadow) start(p Params, chunkSize int, recent *sampleRing) {
+	if s.params == nil {
+		s.params = &p
+	}
+
+	err := CompatibleParams(p, *s.params)
+
+	if err == nil {
+		err = CheckInputShape(s.model, *s.params)
+	}
+
+	if err != nil {
+		s.closed = true
+		s.report.Err = fmt.Errorf("shadow model: %w", err)
+		return
+	}
+
+	config := DefaultThreshold
+	config.Center = s.params.ThresholdCenter
+
+	s.decoder = NewThresholdDecoder(s.params.ThresholdConfig, config)
+	s.detector = NewTriggerDetector(chunkSize, s.detectorOpts...)
+	s.sampleRate = p.SampleRate
+	s.recent = recent
+	s.chunkCh = make(chan shadowChunk, s.budget)
+	s.doneCh = make(chan bool)
+
+	go s.run()
+}
+
+// add records a chunk scored by the production model, and queues it for the shadow model
+func (s *shadow) add(c shadowChunk) {
+	s.lock.Lock()
+
+	if c.productionFired {
+		s.report.ProductionActivations++
+		s.production = append(s.production, c)
+	}
+
+	// Chunks where the production model was skipped have no features to score
+	if c.features == nil || s.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	select {
+	case s.chunkCh <- c:
+	default:
+		s.report.Dropped++
+	}
+
+	s.lock.Unlock()
+}
+
+// run scores chunks until the shadow is closed
+func (s *shadow) run() {
+	defer close(s.doneCh)
+
+	for c := range s.chunkCh {
+		raw, err := s.model.Predict(c.features)
+
+		// A failing candidate mustn't affect production, so its chunks are counted as dropped
+		if err != nil {
+			s.lock.Lock()
+			s.report.Dropped++
+			s.lock.Unlock()
+			continue
+		}
+
+		c.shadow = s.decoder.Decode(raw)
+		fired := s.detector.Update(c.shadow)
+
+		s.lock.Lock()
+
+		s.report.Chunks++
+
+		if c.productionAbove == (c.shadow > 1-s.detector.sensitivity) {
+			s.report.ChunksAgreed++
+		}
+
+		s.scores = append(s.scores, c)
+
+		if fired {
+			s.report.ShadowActivations++
+			s.candidates = append(s.candidates, c)
+		}
+
+		disagreements := s.settle(c.position)
+
+		s.lock.Unlock()
+
+		if s.OnDisagreement != nil {
+			for _, d := range disagreements {
+				s.OnDisagreement(d)
+			}
+		}
+	}
+}
+
+// settle matches activations by the two models, returning those which can no longer be matched
+// now both models have passed position
+func (s *shadow) settle(position int64) []Disagreement {
+	tolerance := int64(s.tolerance.Seconds() * float64(s.sampleRate))
+
+	// Pair each shadow activation with the closest production activation
+	candidates := s.candidates[:0]
+
+	for _, c := range s.candidates {
+		best := -1
+
+		for i, p := range s.production {
+			if distance := absInt64(p.position - c.position); distance <= tolerance && (best < 0 || distance < absInt64(s.production[best].position-c.position)) {
+				best = i
+			}
+		}
+
+		if best >= 0 {
+			s.report.Agreed++
+			s.production = append(s.production[:best], s.production[best+1:]...)
+			continue
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	s.candidates = candidates
+
+	var disagreements []Disagreement
+
+	production := s.production[:0]
+
+	for _, p := range s.production {
+		if p.position+tolerance > position {
+			production = append(production, p)
+			continue
+		}
+
+		s.report.ProductionOnly++
+		disagreements = append(disagreements, s.disagreement(ProductionOnly, p))
+	}
+
+	s.production = production
+
+	candidates = s.candidates[:0]
+
+	for _, c := range s.candidates {
+		if c.position+tolerance > position {
+			candidates = append(candidates, c)
+			continue
+		}
+
+		s.report.ShadowOnly++
+		disagreements = append(disagreements, s.disagreement(ShadowOnly, c))
+	}
+
+	s.candidates = candidates
+
+	// Keep the scores which may be needed for the peaks of pending activations
+	i := 0
+
+	for i < len(s.scores) && s.scores[i].position+2*tolerance < position {
+		i++
+	}
+
+	s.scores = s.scores[i:]
+
+	return disagreements
+}
+
+// disagreement describes an unmatched activation
+func (s *shadow) disagreement(kind DisagreementKind, c shadowChunk) Disagreement {
+	d := Disagreement{
+		Kind:     kind,
+		Time:     c.time,
+		Position: c.position,
+	}
+
+	tolerance := int64(s.tolerance.Seconds() * float64(s.sampleRate))
+
+	for _, score := range s.scores {
+		if absInt64(score.position-c.position) > tolerance {
+			continue
+		}
+
+		if score.production > d.ProductionPeak {
+			d.ProductionPeak = score.production
+		}
+
+		if score.shadow > d.ShadowPeak {
+			d.ShadowPeak = score.shadow
+		}
+	}
+
+	if s.audio > 0 && s.recent != nil {
+		d.Audio = s.recent.Ending(c.position, int(s.audio.Seconds()*float64(s.sampleRate)))
+	}
+
+	return d
+}
+
+// Report returns the comparison so far
+func (s *shadow) Report() ShadowReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.report
+}
+
+// close stops the goroutine, once it has scored any queued chunks, and closes the shadow model
+func (s *shadow) close() error {
+	s.lock.Lock()
+	started := s.chunkCh != nil && !s.closed
+	s.closed = true
+
+	if started {
+		close(s.chunkCh)
+	}
+
+	s.lock.Unlock()
+
+	if started {
+		<-s.doneCh
+	}
+
+	return s.model.Close()
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -0,0 +1,148 @@
+package precise
+
+import (
+	"errors"
+	"gorgonia.org/tensor"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scheduledModel returns a model which predicts 1 on the given calls, and 0 otherwise
+func scheduledModel(calls ...int) Model {
+	lock := new(sync.Mutex)
+	n := 0
+
+	return funcModel(func(inputData tensor.Tensor) (float32, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		n++
+
+		for _, c := range calls {
+			if n == c {
+				return 1, nil
+			}
+		}
+
+		return 0, nil
+	})
+}
+
+func TestRunnerShadow(t *testing.T) {
+	l, err := NewListener(scheduledModel(100, 300), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := new(sync.Mutex)
+
+	var disagreements []Disagreement
+
+	r := NewRunner(l, 320, WithDetectorOpts(WithTriggerLevel(0)), WithShadow(scheduledModel(105, 450),
+		WithShadowDetector(WithTriggerLevel(0)),
+		WithShadowBudget(1000),
+		WithShadowAudio(500*time.Millisecond),
+		WithDisagreementFunc(func(d Disagreement) {
+			lock.Lock()
+			disagreements = append(disagreements, d)
+			lock.Unlock()
+		})))
+
+	chunk := make([]int16, 320)
+
+	for i := 0; i < 600; i++ {
+		r.Queue(chunk)
+	}
+
+	r.Queue(nil)
+
+	// Closing waits for the shadow model to catch up
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := r.ShadowReport()
+
+	if report.Chunks != 600 || report.ProductionActivations != 2 || report.ShadowActivations != 2 {
+		t.Fatalf("expected 600 chunks and 2 activations each, got %+v", report)
+	}
+
+	if report.Agreed != 1 || report.ProductionOnly != 1 || report.ShadowOnly != 1 {
+		t.Fatalf("expected one agreement and one disagreement each way, got %+v", report)
+	}
+
+	if rate := report.AgreementRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("expected an agreement rate of 1/3, got %.2f", rate)
+	}
+
+	if rate := report.ChunkAgreementRate(); rate != 596.0/600 {
+		t.Errorf("expected 596 of 600 chunks to agree, got %.4f", rate)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(disagreements) != 2 {
+		t.Fatalf("expected 2 disagreements, got %d", len(disagreements))
+	}
+
+	if d := disagreements[0]; d.Kind != ProductionOnly || d.Position != 300*320 || d.ProductionPeak < 0.5 || len(d.Audio) != 8000 {
+		t.Errorf("expected the production activation at chunk 300, got %s at %d with %d samples", d.Kind, d.Position/320, len(d.Audio))
+	}
+
+	if d := disagreements[1]; d.Kind != ShadowOnly || d.Position != 450*320 || d.ShadowPeak < 0.5 {
+		t.Errorf("expected the shadow activation at chunk 450, got %s at %d", d.Kind, d.Position/320)
+	}
+}
+
+func TestRunnerShadowIncompatible(t *testing.T) {
+	p := NewParams()
+
+	other := NewParams()
+	other.SampleRate = 8000
+
+	for name, c := range map[string]struct {
+		model Model
+		opts  []ShadowOption
+		err   error
+	}{
+		"params": {&swapModel{score: 1}, []ShadowOption{WithShadowParams(other)}, ErrIncompatibleParams},
+		"shape":  {&swapModel{score: 1, shape: []int{1, p.NFeatures() + 1, p.NMFCC}}, nil, ErrInputShape},
+	} {
+		l, err := NewListener(scheduledModel(), p)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewRunner(l, 320, WithShadow(c.model, c.opts...))
+
+		chunk := make([]int16, 320)
+
+		for i := 0; i < 10; i++ {
+			r.Queue(chunk)
+		}
+
+		r.Queue(nil)
+
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		report := r.ShadowReport()
+
+		if !errors.Is(report.Err, c.err) {
+			t.Errorf("%s: expected %v, got %v", name, c.err, report.Err)
+		}
+
+		if report.Chunks != 0 || report.Dropped != 0 {
+			t.Errorf("%s: expected no chunks to be scored or dropped, got %+v", name, report)
+		}
+
+		if !c.model.(*swapModel).closed {
+			t.Errorf("%s: expected the shadow model to be closed", name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package precise
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrSignature     = errors.New("signature verification failed")
+	ErrNoTrustedKeys = errors.New("no trusted keys")
+)
+
+const (
+	// SignatureExt is appended to a model's path for its detached signature file
+	SignatureExt = ".sig"
+)
+
+// TrustedKeys are the ed25519 public keys models may be signed with
+type TrustedKeys []ed25519.PublicKey
+
+// Verify returns an error unless signature, as written by SignModel, is a signature over data by a trusted key
+func (k TrustedKeys) Verify(data, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrSignature)
+	}
+
+	if len(k) == 0 {
+		return fmt.Errorf("%w: no trusted keys", ErrSignature)
+	}
+
+	for _, key := range k {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: not signed by a trusted key", ErrSignature)
+}
+
+// ReadFile reads a model file, returning its contents only if the signature file beside it is signed by a trusted key
+func (k TrustedKeys) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := os.ReadFile(path + SignatureExt)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w: no signature", path, ErrSignature)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := k.Verify(data, sig); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// SignModel returns a detached signature over data, base64 encoded
+func SignModel(key ed25519.PrivateKey, data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n")
+}
+
+// SignModelFile writes the signature file for a model
+func SignModelFile(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+SignatureExt, SignModel(key, data), 0644)
+}
+
+// VerifiedLoader wraps load so it only loads models signed by a trusted key, such as for a ModelWatcher.
+// The verified contents are what's loaded, so the file can't be changed between verifying and loading.
+func VerifiedLoader(keys TrustedKeys, load ModelLoader) ModelLoader {
+	return func(path string) (Model, error) {
+		data, err := keys.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return loadModelData(data, filepath.Ext(path), load)
+	}
+}
+
+// ParsePublicKey parses a base64 encoded ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key is %d bytes, expected %d", len(key), ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(key), nil
+}
+
+// ReadTrustedKeys reads base64 encoded public keys from a file, one per line, ignoring blank lines and # comments
+func ReadTrustedKeys(path string) (TrustedKeys, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var keys TrustedKeys
+
+	for i, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(line)
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, err := ParsePublicKey(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoTrustedKeys)
+	}
+
+	return keys, nil
+}
+
+// ReadSigningKey reads a private key written by WriteSigningKey
+func ReadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s: private key is %d bytes, expected %d", path, len(seed), ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// WriteSigningKey writes a private key's seed, base64 encoded, readable only by its owner
+func WriteSigningKey(path string, key ed25519.PrivateKey) error {
+	return os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key.Seed())+"\n"), 0600)
+}
+
+// EncodePublicKey returns a public key base64 encoded, as read by ParsePublicKey and ReadTrustedKeys
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
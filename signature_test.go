@@ -0,0 +1,115 @@
+package precise
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newSigningKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return public, private
+}
+
+func TestVerifiedLoader(t *testing.T) {
+	trusted, key := newSigningKey(t)
+	_, untrusted := newSigningKey(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.tflite")
+
+	if err := os.WriteFile(path, []byte{255}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := 0
+
+	load := VerifiedLoader(TrustedKeys{trusted}, func(path string) (Model, error) {
+		loaded++
+		return constantModel(1), nil
+	})
+
+	if _, err := load(path); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected an unsigned model to fail with ErrSignature, got %v", err)
+	}
+
+	if err := SignModelFile(path, untrusted); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := load(path); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected a model signed by an untrusted key to fail with ErrSignature, got %v", err)
+	}
+
+	if err := SignModelFile(path, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := load(path); err != nil {
+		t.Errorf("expected a signed model to load, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte{254}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := load(path); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected a modified model to fail with ErrSignature, got %v", err)
+	}
+
+	if loaded != 1 {
+		t.Errorf("expected only the verified model to be loaded, loaded %d", loaded)
+	}
+}
+
+func TestTrustedKeysFiles(t *testing.T) {
+	public, private := newSigningKey(t)
+
+	dir := t.TempDir()
+
+	if err := WriteSigningKey(filepath.Join(dir, "ops.key"), private); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ReadSigningKey(filepath.Join(dir, "ops.key"))
+
+	if err != nil || !key.Equal(private) {
+		t.Fatalf("expected the signing key to round trip, got %v", err)
+	}
+
+	trusted := "# ops\n" + EncodePublicKey(public) + "\n\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "trusted"), []byte(trusted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ReadTrustedKeys(filepath.Join(dir, "trusted"))
+
+	if err != nil || len(keys) != 1 || !keys[0].Equal(public) {
+		t.Fatalf("expected one trusted key, got %d (%v)", len(keys), err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "trusted"), []byte("# no keys yet\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadTrustedKeys(filepath.Join(dir, "trusted")); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("expected a file without keys to fail with ErrNoTrustedKeys, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "trusted"), []byte("not a key\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadTrustedKeys(filepath.Join(dir, "trusted")); err == nil {
+		t.Error("expected an invalid key to fail")
+	}
+}
@@ -0,0 +1,279 @@
+package precise
+
+import (
+	"errors"
+	"gorgonia.org/tensor"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// swapModel is a model with a fixed score and input shape, which records being closed
+type swapModel struct {
+	score  float32
+	shape  []int
+	closed bool
+}
+
+func (m *swapModel) Predict(inputData tensor.Tensor) (float32, error) {
+	return m.score, nil
+}
+
+func (m *swapModel) InputShape() []int {
+	return m.shape
+}
+
+func (m *swapModel) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestListenerSetModel(t *testing.T) {
+	p := NewParams()
+	old := &swapModel{score: 0, shape: []int{1, p.NFeatures(), p.NMFCC}}
+
+	l, err := NewListener(old, p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.SetModel(&swapModel{shape: []int{1, 49, p.NMFCC}}); !errors.Is(err, ErrInputShape) {
+		t.Fatalf("expected ErrInputShape, got %v", err)
+	}
+
+	// Dynamic batch dimensions fit
+	if err := l.SetModel(&swapModel{score: 1, shape: []int{-1, p.NFeatures(), p.NMFCC}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !old.closed {
+		t.Error("expected the old model to be closed")
+	}
+
+	prob, err := l.Update(make([]int16, 1600))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prob < 0.5 {
+		t.Errorf("expected the new model's score, got %.2f", prob)
+	}
+}
+
+func TestRunnerSwapModel(t *testing.T) {
+	l, err := NewListener(&swapModel{score: 0}, NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := new(sync.Mutex)
+	activations := 0
+
+	r := NewRunner(l, 320, WithActivationFunc(func() {
+		lock.Lock()
+		activations++
+		lock.Unlock()
+	}))
+
+	defer r.Close()
+
+	chunk := make([]int16, 320)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.SwapModel(&swapModel{score: 1})
+	}()
+
+	// The runner keeps processing audio while the model is swapped
+	for i := 0; i < 200; i++ {
+		r.Queue(chunk)
+		time.Sleep(time.Millisecond)
+	}
+
+	r.Queue(nil)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if activations == 0 {
+		t.Error("expected the swapped model to activate")
+	}
+}
+
+func TestModelWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "astra.tflite")
+
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded []string
+	var swapped []Model
+
+	w := NewModelWatcher(dir, func(path string) (Model, error) {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		loaded = append(loaded, string(data))
+
+		return &swapModel{}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		w.Watch("astra.tflite", func(model Model) error {
+			swapped = append(swapped, model)
+			return nil
+		})
+	}
+
+	// The model in use isn't reloaded
+	w.Check()
+	w.Check()
+
+	if len(loaded) != 0 {
+		t.Fatalf("expected no reloads, got %v", loaded)
+	}
+
+	if err := os.WriteFile(path, []byte("v2 model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A changed file is only loaded once it has stopped changing
+	w.Check()
+
+	if len(loaded) != 0 {
+		t.Fatalf("expected the changing file not to be loaded, got %v", loaded)
+	}
+
+	w.Check()
+	w.Check()
+
+	if len(loaded) != 2 || loaded[0] != "v2 model" || len(swapped) != 2 || swapped[0] == swapped[1] {
+		t.Fatalf("expected a new model for each listener, got %v", loaded)
+	}
+}
+
+func TestModelWatcherRetry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "astra.tflite")
+
+	failing := true
+	loads := 0
+
+	var errs []error
+
+	w := NewModelWatcher(dir, func(path string) (Model, error) {
+		loads++
+
+		if failing {
+			return nil, errors.New("invalid model")
+		}
+
+		return &swapModel{}, nil
+	}, WithWatchErrorFunc(func(path string, err error) {
+		errs = append(errs, err)
+	}))
+
+	w.Watch("astra.tflite", func(model Model) error {
+		// Watching from a swap doesn't deadlock, as the files aren't locked while loading
+		w.Watch("other.tflite", func(model Model) error {
+			return nil
+		})
+
+		return nil
+	})
+
+	if err := os.WriteFile(path, []byte("v2 model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Check()
+	w.Check()
+
+	if loads != 1 || len(errs) != 1 {
+		t.Fatalf("expected a failed load, got %d loads and %v", loads, errs)
+	}
+
+	// A file which failed to load is tried again
+	failing = false
+
+	w.Check()
+	w.Check()
+
+	if loads != 2 || len(errs) != 1 {
+		t.Fatalf("expected the file to be loaded once more, got %d loads and %v", loads, errs)
+	}
+
+	w.Close()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModelWatcherPartialRetry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "astra.tflite")
+
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+
+	w := NewModelWatcher(dir, func(path string) (Model, error) {
+		return &swapModel{}, nil
+	}, WithWatchErrorFunc(func(path string, err error) {
+		errs = append(errs, err)
+	}))
+
+	var installed []*swapModel
+
+	w.Watch("astra.tflite", func(model Model) error {
+		installed = append(installed, model.(*swapModel))
+		return nil
+	})
+
+	// The second listener rejects the first model it's given
+	var rejected []*swapModel
+	retried := 0
+
+	w.Watch("astra.tflite", func(model Model) error {
+		if len(rejected) == 0 {
+			rejected = append(rejected, model.(*swapModel))
+			return errors.New("swap failed")
+		}
+
+		retried++
+
+		return nil
+	})
+
+	if err := os.WriteFile(path, []byte("v2 model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Check()
+	w.Check()
+
+	if len(installed) != 1 || len(errs) != 1 || !rejected[0].closed {
+		t.Fatalf("expected one install and a closed rejected model, got %d installs and %v", len(installed), errs)
+	}
+
+	// Only the listener which failed is given the model again
+	w.Check()
+	w.Check()
+
+	if len(installed) != 1 || installed[0].closed || retried != 1 || len(errs) != 1 {
+		t.Fatalf("expected only the failed listener to be retried, got %d installs and %d retries", len(installed), retried)
+	}
+}
@@ -0,0 +1,171 @@
+package precise
+
+import "time"
+
+// VAD is a voice activity detector.
+// Implementations keep any recurrent state between calls, so a VAD should only
+// ever be fed a single stream of audio.
+type VAD interface {
+	// Process returns the probability that the latest samples contain speech
+	Process(samples []int16) (float32, error)
+	// Reset clears any state carried between chunks
+	Reset()
+	Close() error
+}
+
+// SpeechEvent is a change in the speech state of a stream
+type SpeechEvent int
+
+const (
+	// SpeechNone means the speech state did not change
+	SpeechNone SpeechEvent = iota
+	// SpeechStart is emitted when speech begins
+	SpeechStart
+	// SpeechEnd is emitted when speech has ended
+	SpeechEnd
+	// CommandEnd is emitted in place of SpeechEnd for the first end of speech
+	// once speech is heard following an activation, marking the end of the user's command
+	CommandEnd
+)
+
+func (e SpeechEvent) String() string {
+	switch e {
+	case SpeechStart:
+		return "SpeechStart"
+	case SpeechEnd:
+		return "SpeechEnd"
+	case CommandEnd:
+		return "CommandEnd"
+	}
+	return "SpeechNone"
+}
+
+type SpeechFunc func(event SpeechEvent)
+
+type VADOption func(*SpeechGate)
+
+// WithSpeechThreshold sets the probabilities required to start and end speech.
+// Using a lower end threshold avoids flapping around a single value.
+func WithSpeechThreshold(start, end float32) VADOption {
+	return func(g *SpeechGate) {
+		g.startThreshold = start
+		g.endThreshold = end
+	}
+}
+
+// WithSpeechPadding sets how long the probability must stay below the end
+// threshold before speech is considered over
+func WithSpeechPadding(d time.Duration) VADOption {
+	return func(g *SpeechGate) {
+		g.padding = d
+	}
+}
+
+// WithMinSpeech sets how long the probability must stay above the start
+// threshold before speech is considered started
+func WithMinSpeech(d time.Duration) VADOption {
+	return func(g *SpeechGate) {
+		g.minSpeech = d
+	}
+}
+
+// NewSpeechGate creates a new SpeechGate for a VAD
+func NewSpeechGate(vad VAD, sampleRate int, opts ...VADOption) *SpeechGate {
+	g := &SpeechGate{
+		vad:            vad,
+		sampleRate:     sampleRate,
+		startThreshold: 0.5,
+		endThreshold:   0.35,
+		padding:        500 * time.Millisecond,
+		minSpeech:      60 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// SpeechGate turns VAD probabilities into speech start and end events
+type SpeechGate struct {
+	vad            VAD
+	sampleRate     int
+	startThreshold float32
+	endThreshold   float32
+	padding        time.Duration
+	minSpeech      time.Duration
+	speaking       bool
+	voiced         bool
+	speech         int
+	silence        int
+}
+
+// Update passes samples through the VAD and returns the resulting speech event
+func (g *SpeechGate) Update(samples []int16) (SpeechEvent, error) {
+	prob, err := g.vad.Process(samples)
+
+	if err != nil {
+		return SpeechNone, err
+	}
+
+	g.voiced = g.speaking && prob >= g.endThreshold || prob >= g.startThreshold
+
+	if !g.speaking {
+		if prob < g.startThreshold {
+			g.speech = 0
+			return SpeechNone, nil
+		}
+
+		g.speech += len(samples)
+
+		if g.speech < g.samples(g.minSpeech) {
+			return SpeechNone, nil
+		}
+
+		g.speaking = true
+		g.silence = 0
+
+		return SpeechStart, nil
+	}
+
+	if prob >= g.endThreshold {
+		g.silence = 0
+		return SpeechNone, nil
+	}
+
+	g.silence += len(samples)
+
+	if g.silence < g.samples(g.padding) {
+		return SpeechNone, nil
+	}
+
+	g.speaking = false
+	g.speech = 0
+
+	return SpeechEnd, nil
+}
+
+// Speaking returns true while the stream is inside a segment of speech
+func (g *SpeechGate) Speaking() bool {
+	return g.speaking
+}
+
+// Reset clears the gate and the underlying VAD state
+func (g *SpeechGate) Reset() {
+	g.speaking = false
+	g.voiced = false
+	g.speech = 0
+	g.silence = 0
+	g.vad.Reset()
+}
+
+// Close closes the underlying VAD
+func (g *SpeechGate) Close() error {
+	return g.vad.Close()
+}
+
+// samples converts a duration to a number of samples
+func (g *SpeechGate) samples(d time.Duration) int {
+	return int(d.Seconds() * float64(g.sampleRate))
+}
@@ -0,0 +1,167 @@
+//go:build onnx && cgo
+
+package precise
+
+import (
+	"errors"
+	"github.com/ivansuteja96/go-onnxruntime"
+)
+
+var (
+	ErrUnsupportedSampleRate = errors.New("unsupported sample rate")
+)
+
+type SileroOption func(*SileroVAD)
+
+// WithSileroDevice sets the device the VAD model runs on
+func WithSileroDevice(deviceType DeviceType) SileroOption {
+	return func(v *SileroVAD) {
+		v.deviceType = deviceType
+	}
+}
+
+// WithSileroFrameSize overrides the number of samples passed to the model per call.
+// Silero models accept 256, 512 or 768 samples at 8kHz and 512, 1024 or 1536 at 16kHz.
+func WithSileroFrameSize(frameSize int) SileroOption {
+	return func(v *SileroVAD) {
+		v.frameSize = frameSize
+	}
+}
+
+// NewSileroVAD creates a new Silero VAD from an onnx model.
+// The onnxruntime bindings only accept float32 inputs, so the model must take
+// (input, h, c) and return (output, hn, cn), with the sample rate fixed at export.
+func NewSileroVAD(modelPath string, sampleRate int, opts ...SileroOption) (*SileroVAD, error) {
+	v := &SileroVAD{
+		deviceType: OnnxCPU,
+	}
+
+	switch sampleRate {
+	case 8000:
+		v.frameSize = 256
+	case 16000:
+		v.frameSize = 512
+	default:
+		return nil, ErrUnsupportedSampleRate
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	model, err := newONNXSession(modelPath, v.deviceType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v.model = model
+	v.Reset()
+
+	return v, nil
+}
+
+// SileroVAD runs a Silero VAD model, keeping the LSTM state between chunks
+type SileroVAD struct {
+	model      *onnxruntime.ORTSession
+	deviceType DeviceType
+	frameSize  int
+	pending    []int16
+	h, c       []float32
+	prob       float32
+}
+
+// Process buffers samples into model sized frames, returning the probability of
+// the latest complete frame
+func (v *SileroVAD) Process(samples []int16) (float32, error) {
+	if v.model == nil {
+		return -1, ErrModelClosed
+	}
+
+	v.pending = append(v.pending, samples...)
+
+	for len(v.pending) >= v.frameSize {
+		prob, err := v.predict(v.pending[:v.frameSize])
+
+		if err != nil {
+			return -1, err
+		}
+
+		v.prob = prob
+		v.pending = v.pending[v.frameSize:]
+	}
+
+	return v.prob, nil
+}
+
+// predict runs a single frame through the model and stores the new state
+func (v *SileroVAD) predict(frame []int16) (float32, error) {
+	input := make([]float32, len(frame))
+
+	for i, s := range frame {
+		input[i] = float32(float64(s) * int16Divider)
+	}
+
+	res, err := v.model.Predict([]onnxruntime.TensorValue{
+		{
+			Value: input,
+			Shape: []int64{1, int64(len(input))},
+		},
+		{
+			Value: v.h,
+			Shape: []int64{2, 1, 64},
+		},
+		{
+			Value: v.c,
+			Shape: []int64{2, 1, 64},
+		},
+	})
+
+	if err != nil {
+		return -1, err
+	}
+
+	if len(res) < 3 {
+		return -1, errors.New("unexpected number of outputs")
+	}
+
+	output, ok := res[0].Value.([]float32)
+
+	if !ok || len(output) == 0 {
+		return -1, errors.New("unexpected output value type")
+	}
+
+	h, hOk := res[1].Value.([]float32)
+	c, cOk := res[2].Value.([]float32)
+
+	if !hOk || !cOk {
+		return -1, errors.New("unexpected state value type")
+	}
+
+	v.h = h
+	v.c = c
+
+	// Older models output (non-speech, speech), so the last value is always speech
+	return output[len(output)-1], nil
+}
+
+// Reset clears the recurrent state and any buffered samples
+func (v *SileroVAD) Reset() {
+	v.h = make([]float32, 2*64)
+	v.c = make([]float32, 2*64)
+	v.pending = v.pending[:0]
+	v.prob = 0
+}
+
+// Close cleans up the model after use
+func (v *SileroVAD) Close() error {
+	if v.model == nil {
+		return nil
+	}
+
+	err := v.model.Close()
+
+	v.model = nil
+
+	return err
+}
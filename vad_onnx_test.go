@@ -0,0 +1,53 @@
+//go:build onnx && cgo
+
+package precise
+
+import "testing"
+
+// testdata/silero_stub.onnx is a stand-in for a Silero model, returning the
+// peak absolute amplitude of the frame and passing the state through.
+func TestSileroVAD(t *testing.T) {
+	vad, err := NewSileroVAD("testdata/silero_stub.onnx", 16000)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer vad.Close()
+
+	// Half a frame isn't enough to run the model
+	prob, err := vad.Process(make([]int16, 256))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prob != 0 {
+		t.Fatal("expected no probability before a full frame, got", prob)
+	}
+
+	loud := make([]int16, 512)
+	loud[10] = 16384
+
+	prob, err = vad.Process(loud)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first frame is the buffered half frame and the start of loud, which holds its peak
+	if prob != 0.5 {
+		t.Fatal("expected 0.5 for the first frame, got", prob)
+	}
+
+	prob, err = vad.Process(make([]int16, 256))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second frame is the silent end of loud and the silence after it
+	if prob != 0 {
+		t.Fatal("expected a silent second frame, got", prob)
+	}
+}
@@ -0,0 +1,194 @@
+package precise
+
+import (
+	"errors"
+	"gorgonia.org/tensor"
+	"testing"
+	"time"
+)
+
+// scriptedVAD returns a fixed sequence of probabilities
+type scriptedVAD struct {
+	probs []float32
+	calls int
+}
+
+func (v *scriptedVAD) Process(samples []int16) (float32, error) {
+	prob := v.probs[v.calls%len(v.probs)]
+	v.calls++
+	return prob, nil
+}
+
+func (v *scriptedVAD) Reset() {
+	v.calls = 0
+}
+
+func (v *scriptedVAD) Close() error {
+	return nil
+}
+
+// funcModel is a Model backed by a function
+type funcModel func(inputData tensor.Tensor) (float32, error)
+
+func (m funcModel) Predict(inputData tensor.Tensor) (float32, error) {
+	return m(inputData)
+}
+
+func (m funcModel) Close() error {
+	return nil
+}
+
+func TestSpeechGate(t *testing.T) {
+	vad := &scriptedVAD{probs: []float32{0.1, 0.9, 0.9, 0.4, 0.2, 0.2, 0.2, 0.1}}
+
+	// 1600 samples is 100ms at 16kHz
+	gate := NewSpeechGate(vad, 16000, WithMinSpeech(200*time.Millisecond), WithSpeechPadding(300*time.Millisecond))
+
+	expected := []SpeechEvent{SpeechNone, SpeechNone, SpeechStart, SpeechNone, SpeechNone, SpeechNone, SpeechEnd, SpeechNone}
+
+	for i, want := range expected {
+		event, err := gate.Update(make([]int16, 1600))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if event != want {
+			t.Fatalf("chunk %d: expected %s, got %s", i, want, event)
+		}
+	}
+}
+
+func TestRunnerVADGate(t *testing.T) {
+	p := NewParams()
+
+	predictions := 0
+
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		predictions++
+		return 0, nil
+	}), p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vad := &scriptedVAD{probs: []float32{0, 0, 1, 1, 1, 0, 0, 0}}
+
+	var events []SpeechEvent
+
+	done := make(chan struct{})
+
+	r := NewRunner(l, 1600,
+		WithVAD(vad, WithMinSpeech(0), WithSpeechPadding(200*time.Millisecond)),
+		WithSpeechFunc(func(event SpeechEvent) {
+			events = append(events, event)
+		}),
+		WithExitFunc(func(err error) {
+			close(done)
+		}),
+	)
+
+	for i := 0; i < len(vad.probs); i++ {
+		r.Queue(make([]int16, 1600))
+	}
+
+	r.Close()
+	<-done
+
+	if len(events) != 2 || events[0] != SpeechStart || events[1] != SpeechEnd {
+		t.Fatal("unexpected speech events", events)
+	}
+
+	// Three chunks of speech, the padding and the chunk where speech ended
+	if predictions != 5 {
+		t.Fatal("expected 5 predictions, got", predictions)
+	}
+}
+
+// failingVAD fails to close
+type failingVAD struct {
+	scriptedVAD
+}
+
+func (v *failingVAD) Close() error {
+	return errors.New("close failed")
+}
+
+func TestRunnerCloseAfterError(t *testing.T) {
+	model := &swapModel{}
+
+	l, err := NewListener(model, NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner(l, 1600, WithVAD(&failingVAD{scriptedVAD{probs: []float32{0}}}))
+
+	if err := r.Close(); err == nil {
+		t.Error("expected the VAD's close error")
+	}
+
+	if !model.closed {
+		t.Error("expected the model to be closed despite the VAD failing to close")
+	}
+}
+
+func TestRunnerCommandEnd(t *testing.T) {
+	calls := 0
+
+	// Activate at the end of the wake word
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls <= 4 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The wake word, a pause, the command and silence
+	vad := &scriptedVAD{probs: []float32{1, 1, 1, 1, 0, 0, 0, 1, 1, 0, 0, 0}}
+
+	var events []string
+
+	done := make(chan struct{})
+
+	r := NewRunner(l, 1600,
+		WithVAD(vad, WithMinSpeech(0), WithSpeechPadding(200*time.Millisecond)),
+		WithActivationFunc(func() {
+			events = append(events, "Activation")
+		}),
+		WithSpeechFunc(func(event SpeechEvent) {
+			events = append(events, event.String())
+		}),
+		WithExitFunc(func(err error) {
+			close(done)
+		}),
+	)
+
+	for i := 0; i < len(vad.probs); i++ {
+		r.Queue(make([]int16, 1600))
+	}
+
+	r.Close()
+	<-done
+
+	expected := []string{"SpeechStart", "Activation", "SpeechEnd", "SpeechStart", "CommandEnd"}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package precise
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Verification is the result of re-scoring an activation with a verifier
+type Verification struct {
+	Score    float32
+	Accepted bool
+	// TimedOut is set when the verifier didn't finish within the timeout, or was still scoring an
+	// earlier activation which timed out, and Accepted follows WithAcceptOnTimeout
+	TimedOut bool
+	Latency  time.Duration
+}
+
+// VerificationFunc is called with the result of each verification, including rejected activations
+type VerificationFunc func(a Activation, v Verification)
+
+type VerifierOption func(*verifier)
+
+// WithVerifierThreshold sets the score, from 0 to 1, the verifier must reach to accept an activation
+func WithVerifierThreshold(threshold float32) VerifierOption {
+	return func(v *verifier) {
+		v.threshold = threshold
+	}
+}
+
+// WithVerifierTimeout sets the latency budget for verification
+func WithVerifierTimeout(d time.Duration) VerifierOption {
+	return func(v *verifier) {
+		v.timeout = d
+	}
+}
+
+// WithAcceptOnTimeout sets whether activations are accepted when the verifier times out
+func WithAcceptOnTimeout(accept bool) VerifierOption {
+	return func(v *verifier) {
+		v.acceptOnTimeout = accept
+	}
+}
+
+// newVerifier creates a verifier scoring activations with listener
+func newVerifier(listener *Listener, opts ...VerifierOption) *verifier {
+	v := &verifier{
+		listener:  listener,
+		threshold: 0.5,
+		timeout:   500 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// verifier re-scores candidate activations with a second, usually larger, model
+type verifier struct {
+	listener        *Listener
+	threshold       float32
+	timeout         time.Duration
+	acceptOnTimeout bool
+	// scoring is set while the listener is scoring, which may outlast the timeout
+	scoring atomic.Bool
+}
+
+// verifiedActivation is an activation with the result of its verification
+type verifiedActivation struct {
+	activation   Activation
+	verification Verification
+	err          error
+}
+
+// verify scores audio in the background, sending the result to results unless done is closed first
+func (v *verifier) verify(a Activation, audio []int16, results chan<- verifiedActivation, done <-chan bool) {
+	res := verifiedActivation{activation: a}
+
+	// Scores can't be cancelled, so while one which timed out is still running, activations aren't scored
+	if v.scoring.CompareAndSwap(false, true) {
+		res.verification, res.err = v.score(audio)
+	} else {
+		res.verification = Verification{
+			Accepted: v.acceptOnTimeout,
+			TimedOut: true,
+		}
+	}
+
+	select {
+	case results <- res:
+	case <-done:
+	}
+}
+
+// score scores audio with the listener, giving up after the timeout
+func (v *verifier) score(audio []int16) (Verification, error) {
+	start := time.Now()
+
+	type score struct {
+		score float32
+		err   error
+	}
+
+	// The result channel is buffered, so a score finishing after the timeout doesn't block
+	scored := make(chan score, 1)
+
+	go func() {
+		s, err := v.listener.Score(audio)
+
+		v.scoring.Store(false)
+
+		scored <- score{s, err}
+	}()
+
+	select {
+	case s := <-scored:
+		return Verification{
+			Score:    s.score,
+			Accepted: s.score >= v.threshold,
+			Latency:  time.Since(start),
+		}, s.err
+	case <-time.After(v.timeout):
+		return Verification{
+			Accepted: v.acceptOnTimeout,
+			TimedOut: true,
+			Latency:  v.timeout,
+		}, nil
+	}
+}
@@ -0,0 +1,124 @@
+package precise
+
+import (
+	"gorgonia.org/tensor"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunnerVerifier(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		score       float32
+		delay       time.Duration
+		opts        []VerifierOption
+		activations int
+		timedOut    bool
+	}{
+		{"accepted", 1, 0, []VerifierOption{WithVerifierTimeout(5 * time.Second)}, 1, false},
+		{"rejected", 0, 0, []VerifierOption{WithVerifierTimeout(5 * time.Second)}, 0, false},
+		{"timeout accepted", 1, 200 * time.Millisecond, []VerifierOption{WithVerifierTimeout(20 * time.Millisecond), WithAcceptOnTimeout(true)}, 1, true},
+		{"timeout rejected", 1, 200 * time.Millisecond, []VerifierOption{WithVerifierTimeout(20 * time.Millisecond)}, 0, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			verifier, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+				time.Sleep(c.delay)
+
+				return c.score, nil
+			}), NewParams())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifications := make(chan Verification, 1)
+
+			r, results := newTriggeringRunner(t, WithVerifier(verifier, c.opts...), WithVerificationFunc(func(a Activation, v Verification) {
+				verifications <- v
+			}))
+
+			audio := modulatedTone(1, 48000)
+
+			for i := 0; i < len(audio); i += 320 {
+				r.Queue(audio[i : i+320])
+			}
+
+			select {
+			case v := <-verifications:
+				if v.TimedOut != c.timedOut {
+					t.Errorf("expected timed out to be %v, got %v", c.timedOut, v.TimedOut)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for verification")
+			}
+
+			if activations := results(); activations != c.activations {
+				t.Fatalf("expected %d activations, got %d", c.activations, activations)
+			}
+		})
+	}
+}
+
+func TestVerifierBusy(t *testing.T) {
+	lock := new(sync.Mutex)
+	calls := 0
+
+	started := make(chan bool, 2)
+	release := make(chan struct{})
+
+	listener, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		lock.Lock()
+		calls++
+		lock.Unlock()
+
+		started <- true
+		<-release
+
+		return 1, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := newVerifier(listener, WithVerifierTimeout(10*time.Millisecond), WithAcceptOnTimeout(true))
+
+	results := make(chan verifiedActivation, 3)
+	done := make(chan bool)
+
+	audio := make([]int16, NewParams().BufferSamples())
+
+	// The first score times out and keeps running, so the second activation isn't scored
+	for i := 0; i < 2; i++ {
+		v.verify(Activation{}, audio, results, done)
+
+		if res := <-results; !res.verification.TimedOut || !res.verification.Accepted {
+			t.Fatalf("expected verification %d to time out and be accepted, got %+v", i, res.verification)
+		}
+	}
+
+	<-started
+
+	lock.Lock()
+	scored := calls
+	lock.Unlock()
+
+	if scored != 1 {
+		t.Fatal("expected a single score while the first was running, got", scored)
+	}
+
+	// Once the first score finishes, activations are scored again
+	close(release)
+
+	for v.scoring.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	v.timeout = 5 * time.Second
+	v.verify(Activation{}, audio, results, done)
+
+	if res := <-results; res.verification.TimedOut || res.verification.Score != 1 {
+		t.Fatalf("expected the activation to be scored, got %+v", res.verification)
+	}
+}
@@ -0,0 +1,232 @@
+package precise
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ModelLoader loads a model from a file
+type ModelLoader func(path string) (Model, error)
+
+// ModelSwapFunc installs a newly loaded model, such as Runner.SwapModel or Listener.SetModel
+type ModelSwapFunc func(model Model) error
+
+// WatchErrorFunc is called when a changed model can't be loaded or installed
+type WatchErrorFunc func(path string, err error)
+
+type WatcherOption func(*ModelWatcher)
+
+// WithWatchInterval sets how often the directory is checked for changes
+func WithWatchInterval(d time.Duration) WatcherOption {
+	return func(w *ModelWatcher) {
+		w.interval = d
+	}
+}
+
+// WithWatchErrorFunc sets the func called when a changed model can't be loaded or installed
+func WithWatchErrorFunc(f WatchErrorFunc) WatcherOption {
+	return func(w *ModelWatcher) {
+		w.OnError = f
+	}
+}
+
+// NewModelWatcher creates a watcher reloading models in dir with load when their files change.
+// Files are checked by polling, and only reloaded once they have stopped changing, so a model
+// being copied into place isn't loaded half written.
+func NewModelWatcher(dir string, load ModelLoader, opts ...WatcherOption) *ModelWatcher {
+	w := &ModelWatcher{
+		dir:       dir,
+		load:      load,
+		interval:  2 * time.Second,
+		files:     make(map[string]*watchedFile),
+		closeCh:   make(chan bool),
+		closeOnce: new(sync.Once),
+		checkLock: new(sync.Mutex),
+		lock:      new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// ModelWatcher reloads models when files in a directory change, installing them into running listeners
+type ModelWatcher struct {
+	dir       string
+	load      ModelLoader
+	interval  time.Duration
+	files     map[string]*watchedFile
+	closeCh   chan bool
+	closeOnce *sync.Once
+	// checkLock is held while checking, so models aren't loaded twice, and lock while using files
+	checkLock *sync.Mutex
+	lock      *sync.Mutex
+
+	OnError WatchErrorFunc
+}
+
+// watchedFile is a model file, with the funcs installing it
+type watchedFile struct {
+	swaps []*watchedSwap
+	// seen is the file state at the last check
+	seen os.FileInfo
+}
+
+// watchedSwap is a func installing a model, with the file state it last installed
+type watchedSwap struct {
+	swap   ModelSwapFunc
+	loaded os.FileInfo
+}
+
+// Watch installs the model in the file name, within the directory, with swap whenever it changes.
+// Each swap is given its own model, as models may not be safe to share between listeners.
+func (w *ModelWatcher) Watch(name string, swap ModelSwapFunc) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	s := &watchedSwap{swap: swap}
+
+	// The current file is already in use, so only later changes are loaded
+	info, err := os.Stat(filepath.Join(w.dir, name))
+
+	if err == nil {
+		s.loaded = info
+	}
+
+	f, ok := w.files[name]
+
+	if !ok {
+		f = &watchedFile{}
+
+		if err == nil {
+			f.seen = info
+		}
+
+		w.files[name] = f
+	}
+
+	f.swaps = append(f.swaps, s)
+}
+
+// Start checks for changes in the background until Close
+func (w *ModelWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.closeCh:
+				return
+			}
+		}
+	}()
+}
+
+// watchedChange is a changed file to load, with the swaps which haven't installed it when it was found
+type watchedChange struct {
+	path  string
+	info  os.FileInfo
+	swaps []*watchedSwap
+}
+
+// Check reloads any models whose files have changed and stopped changing since the last check.
+// A model which fails to load or install is tried again at the next check, only for the swaps which failed.
+func (w *ModelWatcher) Check() {
+	w.checkLock.Lock()
+	defer w.checkLock.Unlock()
+
+	// Loading may be slow, so the files are only locked while finding the changes
+	for _, c := range w.changes() {
+		for _, s := range c.swaps {
+			model, err := w.load(c.path)
+
+			if err == nil {
+				err = s.swap(model)
+
+				// A model which wasn't installed is no longer needed
+				if err != nil {
+					model.Close()
+				}
+			}
+
+			if err != nil {
+				if w.OnError != nil {
+					w.OnError(c.path, err)
+				}
+
+				continue
+			}
+
+			w.lock.Lock()
+			s.loaded = c.info
+			w.lock.Unlock()
+		}
+	}
+}
+
+// changes returns the files which have changed and stopped changing since the last check
+func (w *ModelWatcher) changes() []watchedChange {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var changes []watchedChange
+
+	for name, f := range w.files {
+		path := filepath.Join(w.dir, name)
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			// A model being replaced may briefly not exist
+			f.seen = nil
+			continue
+		}
+
+		stable := f.seen != nil && sameFile(info, f.seen)
+		f.seen = info
+
+		if !stable {
+			continue
+		}
+
+		var swaps []*watchedSwap
+
+		for _, s := range f.swaps {
+			if s.loaded == nil || !sameFile(info, s.loaded) {
+				swaps = append(swaps, s)
+			}
+		}
+
+		if len(swaps) == 0 {
+			continue
+		}
+
+		changes = append(changes, watchedChange{
+			path:  path,
+			info:  info,
+			swaps: swaps,
+		})
+	}
+
+	return changes
+}
+
+// Close stops checking for changes. It may be called more than once.
+func (w *ModelWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.closeCh)
+	})
+
+	return nil
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
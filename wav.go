@@ -0,0 +1,309 @@
+package precise
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+var (
+	ErrInvalidWAV = errors.New("invalid wav file")
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+	// wavMaxFmtSize bounds the fmt chunk, which is 40 bytes at most for known formats
+	wavMaxFmtSize = 1024
+)
+
+func init() {
+	RegisterDecoder(wavDecoder{})
+}
+
+// wavDecoder is the Decoder for wav files
+type wavDecoder struct{}
+
+func (wavDecoder) Match(header []byte) bool {
+	return len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE"
+}
+
+func (wavDecoder) Decode(r io.Reader) (AudioStream, error) {
+	return NewWAVStream(r)
+}
+
+// ReadWAVFile reads and decodes a wav file
+func ReadWAVFile(path string) (*Audio, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ReadWAV(f)
+}
+
+// ReadWAV decodes an entire wav stream
+func ReadWAV(r io.Reader) (*Audio, error) {
+	s, err := NewWAVStream(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadAllAudio(s)
+}
+
+// NewWAVStream reads the header of a wav stream, returning a stream of its audio.
+// PCM (8, 16, 24 and 32-bit) and IEEE float (32 and 64-bit) encodings are supported.
+func NewWAVStream(r io.Reader) (*WAVStream, error) {
+	var header [12]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: missing RIFF header", ErrInvalidWAV)
+	}
+
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: not a RIFF/WAVE file", ErrInvalidWAV)
+	}
+
+	var format, channels, bits uint16
+	var sampleRate uint32
+	var haveFormat bool
+
+	for {
+		var chunk [8]byte
+
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+		}
+
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		// Chunks are padded to an even size, which can overflow a uint32
+		padded := int64(size) + int64(size%2)
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("%w: fmt chunk too short", ErrInvalidWAV)
+			}
+
+			if size > wavMaxFmtSize {
+				return nil, fmt.Errorf("%w: fmt chunk of %d bytes", ErrInvalidWAV, size)
+			}
+
+			body := make([]byte, padded)
+
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("%w: truncated fmt chunk", ErrInvalidWAV)
+			}
+
+			format = binary.LittleEndian.Uint16(body[0:2])
+			channels = binary.LittleEndian.Uint16(body[2:4])
+			sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			bits = binary.LittleEndian.Uint16(body[14:16])
+
+			if format == wavFormatExtensible {
+				if size < 40 {
+					return nil, fmt.Errorf("%w: extensible fmt chunk too short", ErrInvalidWAV)
+				}
+
+				// The sub format GUID starts with the actual format tag
+				format = binary.LittleEndian.Uint16(body[24:26])
+			}
+
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWAV)
+			}
+
+			if channels == 0 || sampleRate == 0 {
+				return nil, fmt.Errorf("%w: %d channels at %dHz", ErrInvalidWAV, channels, sampleRate)
+			}
+
+			decode, err := wavSampleDecoder(format, bits)
+
+			if err != nil {
+				return nil, err
+			}
+
+			// Streamed wav files may not know the size of their data
+			if size != 0 && size != math.MaxUint32 {
+				r = io.LimitReader(r, int64(size))
+			}
+
+			return &WAVStream{
+				r:          r,
+				decode:     decode,
+				sampleRate: int(sampleRate),
+				channels:   int(channels),
+				frameSize:  int(bits) / 8 * int(channels),
+			}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+				return nil, fmt.Errorf("%w: truncated %q chunk", ErrInvalidWAV, id)
+			}
+		}
+	}
+}
+
+// WAVStream is the AudioStream of a wav file's data chunk
+type WAVStream struct {
+	r          io.Reader
+	decode     func(b []byte) float32
+	sampleRate int
+	channels   int
+	frameSize  int
+	buf        []byte
+}
+
+func (s *WAVStream) SampleRate() int {
+	return s.sampleRate
+}
+
+func (s *WAVStream) Channels() int {
+	return s.channels
+}
+
+// ReadSamples decodes whole frames into buf
+func (s *WAVStream) ReadSamples(buf []float32) (int, error) {
+	frames := len(buf) / s.channels
+
+	if need := frames * s.frameSize; len(s.buf) < need {
+		s.buf = make([]byte, need)
+	}
+
+	n, err := io.ReadFull(s.r, s.buf[:frames*s.frameSize])
+
+	// A truncated data chunk ends the stream at the last whole frame
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	width := s.frameSize / s.channels
+	samples := n / s.frameSize * s.channels
+
+	for i := 0; i < samples; i++ {
+		buf[i] = s.decode(s.buf[i*width:])
+	}
+
+	return samples, err
+}
+
+// wavSampleDecoder returns a func decoding a single sample of the given format
+func wavSampleDecoder(format, bits uint16) (func(b []byte) float32, error) {
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		return func(b []byte) float32 {
+			return (float32(b[0]) - 128) / 128
+		}, nil
+	case format == wavFormatPCM && bits == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		}, nil
+	case format == wavFormatPCM && bits == 24:
+		return func(b []byte) float32 {
+			return float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		}, nil
+	case format == wavFormatPCM && bits == 32:
+		return func(b []byte) float32 {
+			return float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648)
+		}, nil
+	case format == wavFormatFloat && bits == 32:
+		return func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}, nil
+	case format == wavFormatFloat && bits == 64:
+		return func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: wav format %d with %d-bit samples", ErrUnsupportedEncoding, format, bits)
+}
+
+// WriteWAV writes 16-bit mono samples as a complete wav file
+func WriteWAV(w io.Writer, samples []int16, sampleRate int) error {
+	if _, err := w.Write(wavHeader(sampleRate, 1, len(samples)*2)); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.LittleEndian, samples)
+}
+
+// NewWAVWriter creates a WAVWriter, writing a header that is completed on Close
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels int) (*WAVWriter, error) {
+	if _, err := w.Write(wavHeader(sampleRate, channels, 0)); err != nil {
+		return nil, err
+	}
+
+	return &WAVWriter{
+		w:          w,
+		sampleRate: sampleRate,
+		channels:   channels,
+	}, nil
+}
+
+// WAVWriter writes 16-bit samples to a wav file of unknown length, such as a recording
+type WAVWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	channels   int
+	written    int
+}
+
+// WriteSamples writes interleaved samples to the file
+func (w *WAVWriter) WriteSamples(samples []int16) error {
+	if err := binary.Write(w.w, binary.LittleEndian, samples); err != nil {
+		return err
+	}
+
+	w.written += len(samples) * 2
+
+	return nil
+}
+
+// Close rewrites the header with the final length. It does not close the underlying writer.
+func (w *WAVWriter) Close() error {
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(wavHeader(w.sampleRate, w.channels, w.written)); err != nil {
+		return err
+	}
+
+	_, err := w.w.Seek(0, io.SeekEnd)
+
+	return err
+}
+
+// wavHeader creates a 44 byte header for 16-bit PCM data
+func wavHeader(sampleRate, channels, dataSize int) []byte {
+	header := make([]byte, 44)
+
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*channels*2))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(channels*2))
+	binary.LittleEndian.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+
+	return header
+}
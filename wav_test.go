@@ -0,0 +1,206 @@
+package precise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"gorgonia.org/tensor"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVRoundTrip(t *testing.T) {
+	samples := sequence(-500, 1000)
+
+	var buf bytes.Buffer
+
+	if err := WriteWAV(&buf, samples, 16000); err != nil {
+		t.Fatal(err)
+	}
+
+	audio, err := ReadWAV(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audio.SampleRate != 16000 || audio.Channels != 1 {
+		t.Fatal("unexpected format", audio.SampleRate, audio.Channels)
+	}
+
+	converted, err := ConvertAudio(audio, NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalSamples(converted, samples) {
+		t.Fatal("samples changed in round trip")
+	}
+}
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.wav")
+
+	f, err := os.Create(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWAVWriter(f, 8000, 2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.WriteSamples(sequence(i*100, 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	audio, err := ReadWAVFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audio.SampleRate != 8000 || audio.Channels != 2 || len(audio.Samples) != 300 {
+		t.Fatal("unexpected audio", audio.SampleRate, audio.Channels, len(audio.Samples))
+	}
+}
+
+func TestReadWAVErrors(t *testing.T) {
+	if _, err := ReadWAV(bytes.NewReader([]byte("RIFX\x00\x00\x00\x00WAVE"))); !errors.Is(err, ErrInvalidWAV) {
+		t.Fatal("expected invalid wav error, got", err)
+	}
+
+	if _, err := ReadWAV(bytes.NewReader(wavHeader(16000, 1, 0)[:20])); !errors.Is(err, ErrInvalidWAV) {
+		t.Fatal("expected invalid wav error for truncated header, got", err)
+	}
+
+	// A-law is not supported by the wav reader
+	header := wavHeader(8000, 1, 4)
+	binary.LittleEndian.PutUint16(header[20:22], 6)
+	binary.LittleEndian.PutUint16(header[34:36], 8)
+
+	if _, err := ReadWAV(bytes.NewReader(append(header, 1, 2, 3, 4))); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatal("expected unsupported encoding error, got", err)
+	}
+}
+
+func TestReadWAVMalformedHeaders(t *testing.T) {
+	// Sizes near the uint32 limit mustn't wrap when padded
+	header := wavHeader(16000, 1, 0)
+	binary.LittleEndian.PutUint32(header[16:20], math.MaxUint32)
+
+	if _, err := ReadWAV(bytes.NewReader(header)); !errors.Is(err, ErrInvalidWAV) {
+		t.Error("expected invalid wav error for a 4GB fmt chunk, got", err)
+	}
+
+	header = wavHeader(16000, 1, 0)
+	binary.LittleEndian.PutUint32(header[16:20], 2048)
+
+	if _, err := ReadWAV(bytes.NewReader(header)); !errors.Is(err, ErrInvalidWAV) {
+		t.Error("expected invalid wav error for an oversized fmt chunk, got", err)
+	}
+
+	// An unknown chunk claiming to be 4GB is skipped without allocating it
+	chunk := append([]byte("RIFF\x00\x00\x00\x00WAVEjunk"), 0xFF, 0xFF, 0xFF, 0xFF, 1, 2, 3)
+
+	if _, err := ReadWAV(bytes.NewReader(chunk)); !errors.Is(err, ErrInvalidWAV) {
+		t.Error("expected invalid wav error for a truncated chunk, got", err)
+	}
+}
+
+func TestConvertAudio(t *testing.T) {
+	// One second of a 1kHz tone in 48kHz stereo
+	audio := &Audio{
+		SampleRate: 48000,
+		Channels:   2,
+		Samples:    make([]float32, 2*48000),
+	}
+
+	for i := 0; i < 48000; i++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/48000))
+		audio.Samples[i*2] = v
+		audio.Samples[i*2+1] = v
+	}
+
+	samples, err := ConvertAudio(audio, NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) < 15990 || len(samples) > 16010 {
+		t.Fatal("expected 16000 samples, got", len(samples))
+	}
+
+	// A 0.5 amplitude sine has an RMS of ~0.354
+	if level := rms(samples[1000:15000]); math.Abs(level-0.354) > 0.01 {
+		t.Fatal("unexpected level after resampling", level)
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.wav")
+
+	f, err := os.Create(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]int16, 32000)
+
+	if err := WriteWAV(f, samples, 16000); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	calls := 0
+
+	// Activate after the first second
+	l, err := NewListener(funcModel(func(inputData tensor.Tensor) (float32, error) {
+		calls++
+
+		if calls > 16 {
+			return 1, nil
+		}
+
+		return 0, nil
+	}), NewParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := l.ProcessFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Predictions) != 32 {
+		t.Fatal("expected 32 predictions, got", len(res.Predictions))
+	}
+
+	if len(res.Activations) != 1 {
+		t.Fatal("expected a single activation, got", len(res.Activations))
+	}
+
+	if offset := res.Activations[0].Offset().Seconds(); offset < 1 || offset > 1.5 {
+		t.Fatal("unexpected activation offset", offset)
+	}
+}